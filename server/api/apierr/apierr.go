@@ -0,0 +1,157 @@
+package apierr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+const ContentType = "application/problem+json"
+
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeConflict         Code = "conflict"
+	CodeForbidden        Code = "forbidden"
+	CodeInternal         Code = "internal"
+	CodeNotFound         Code = "not_found"
+	CodeTimeout          Code = "timeout"
+	CodeTooManyRequests  Code = "too_many_requests"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeUnavailable      Code = "unavailable"
+	CodeUpstreamFailed   Code = "upstream_failed"
+	CodeValidationFailed Code = "validation_failed"
+)
+
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Fields  []types.FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + " " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(400, CodeBadRequest, message)
+}
+
+func Conflict(message string) *Error {
+	return New(409, CodeConflict, message)
+}
+
+func Field(field, message string) *Error {
+	return Validation(message, types.FieldError{Field: field, Message: message})
+}
+
+func Forbidden(message string) *Error {
+	return New(403, CodeForbidden, message)
+}
+
+func Internal(err error) *Error {
+	return &Error{Status: 500, Code: CodeInternal, Message: "Internal server error.", Err: err}
+}
+
+func NotFound(message string) *Error {
+	return New(404, CodeNotFound, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(429, CodeTooManyRequests, message)
+}
+
+func Timeout(err error) *Error {
+	return &Error{Status: 504, Code: CodeTimeout, Message: "The request timed out.", Err: err}
+}
+
+func Unavailable(err error) *Error {
+	return &Error{Status: 503, Code: CodeUnavailable, Message: "Service is temporarily unavailable.", Err: err}
+}
+
+func Unauthorized(message string) *Error {
+	return New(401, CodeUnauthorized, message)
+}
+
+func Upstream(message string, err error) *Error {
+	return &Error{Status: 502, Code: CodeUpstreamFailed, Message: message, Err: err}
+}
+
+func Validation(message string, fields ...types.FieldError) *Error {
+	return &Error{Status: 400, Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+func From(err error) *Error {
+	e := &Error{}
+
+	if errors.As(err, &e) {
+		return e
+	}
+
+	switch {
+	case errors.Is(err, models.ErrConflict):
+		return Conflict("Resource already exists.")
+	case errors.Is(err, models.ErrTooLong):
+		return BadRequest("Value is too long.")
+	case errors.Is(err, models.ErrUnknownUser):
+		return NotFound("Unable to find user.")
+	}
+
+	var t interface {
+		Timeout() bool
+		Temporary() bool
+	}
+
+	if errors.As(err, &t) && t.Temporary() {
+		if t.Timeout() {
+			return Timeout(err)
+		}
+		return Unavailable(err)
+	}
+
+	return Internal(err)
+}
+
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		Write(c, From(c.Errors.Last().Err))
+	}
+}
+
+func Write(c *gin.Context, e *Error) {
+	c.Header("Content-Type", ContentType)
+	c.Render(e.Status, render.JSON{Data: types.ErrorResponseBody{
+		Type:     "about:blank",
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Message,
+		Instance: c.Request.URL.Path,
+		Code:     string(e.Code),
+		Message:  e.Message,
+		Errors:   e.Fields,
+	}})
+}
@@ -0,0 +1,118 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func serve(handler gin.HandlerFunc) (*httptest.ResponseRecorder, *types.ErrorResponseBody) {
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/", handler)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	body := &types.ErrorResponseBody{}
+	json.Unmarshal(rr.Body.Bytes(), body)
+
+	return rr, body
+}
+
+func TestMiddlewareWritesProblem(t *testing.T) {
+	rr, body := serve(func(c *gin.Context) {
+		c.Error(Field("title", "Title is required."))
+	})
+
+	if rr.Code != 400 || rr.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	if body.Code != "validation_failed" || body.Title != "Bad Request" || len(body.Errors) != 1 || body.Errors[0].Field != "title" {
+		t.Fatalf("got %+v", body)
+	}
+}
+
+func TestMiddlewareHidesUnknownErrors(t *testing.T) {
+	rr, body := serve(func(c *gin.Context) {
+		c.Error(errors.New("pq: connection refused"))
+	})
+
+	if rr.Code != 500 || body.Code != "internal" || body.Message != "Internal server error." {
+		t.Fatalf("got %d %+v", rr.Code, body)
+	}
+}
+
+func TestMiddlewareUnwrapsErrors(t *testing.T) {
+	rr, body := serve(func(c *gin.Context) {
+		c.Error(fmt.Errorf("loading article: %w", NotFound("Unable to find article.")))
+	})
+
+	if rr.Code != 404 || body.Code != "not_found" {
+		t.Fatalf("got %d %+v", rr.Code, body)
+	}
+}
+
+func TestMiddlewareMapsStoreErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   Code
+	}{
+		{models.ErrConflict, 409, CodeConflict},
+		{models.ErrTooLong, 400, CodeBadRequest},
+		{fmt.Errorf("creating article: %w", models.ErrUnknownUser), 404, CodeNotFound},
+	}
+
+	for _, test := range tests {
+		rr, body := serve(func(c *gin.Context) {
+			c.Error(test.err)
+		})
+
+		if rr.Code != test.status || body.Code != string(test.code) {
+			t.Fatalf("%v: got %d %+v", test.err, rr.Code, body)
+		}
+	}
+}
+
+func TestMiddlewareLeavesWrittenResponses(t *testing.T) {
+	rr, _ := serve(func(c *gin.Context) {
+		c.JSON(200, gin.H{})
+		c.Error(errors.New("late"))
+	})
+
+	if rr.Code != 200 || rr.Body.String() != "{}" {
+		t.Fatalf("got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+type timeoutError struct{ timeout bool }
+
+func (e timeoutError) Error() string   { return "database is slow" }
+func (e timeoutError) Timeout() bool   { return e.timeout }
+func (e timeoutError) Temporary() bool { return true }
+
+func TestMiddlewareReportsTransientErrors(t *testing.T) {
+	rr, body := serve(func(c *gin.Context) {
+		c.Error(fmt.Errorf("loading article: %w", timeoutError{timeout: true}))
+	})
+
+	if rr.Code != 504 || body.Code != "timeout" {
+		t.Fatalf("got %d %+v", rr.Code, body)
+	}
+
+	rr, body = serve(func(c *gin.Context) {
+		c.Error(timeoutError{})
+	})
+
+	if rr.Code != 503 || body.Code != "unavailable" {
+		t.Fatalf("got %d %+v", rr.Code, body)
+	}
+}
@@ -0,0 +1,30 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const Prefix = "cmp_"
+
+func Generate() (key, prefix string, err error) {
+	b := make([]byte, 24)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	key = Prefix + hex.EncodeToString(b)
+	return key, key[:len(Prefix)+8], nil
+}
+
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsKey(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
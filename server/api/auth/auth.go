@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+const userKey = "user"
+
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+type CurrentUser struct {
+	ID        int
+	Username  string
+	SessionID string
+	Scopes    []string
+}
+
+func (u *CurrentUser) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func SetUser(c *gin.Context, u *CurrentUser) {
+	c.Set(userKey, u)
+}
+
+func UserFrom(c *gin.Context) (*CurrentUser, bool) {
+	v, ok := c.Get(userKey)
+
+	if !ok {
+		return nil, false
+	}
+
+	u, ok := v.(*CurrentUser)
+	return u, ok
+}
+
+func MustUser(c *gin.Context) *CurrentUser {
+	u, ok := UserFrom(c)
+
+	if !ok {
+		panic("auth: no authenticated user in context")
+	}
+
+	return u
+}
+
+func BearerToken(header string) (string, bool) {
+	const prefix = "Bearer "
+
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func ParseSessionToken(ks *keys.KeySet, tokenString string) (*types.JWTClaims, error) {
+	claims := &types.JWTClaims{}
+	t, err := jwt.ParseWithClaims(tokenString, claims, ks.Keyfunc)
+
+	if err != nil || !t.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != types.TokenIssuer || claims.Audience != "" || claims.Id == "" || claims.ID <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func TestBearerToken(t *testing.T) {
+	cases := []struct {
+		header string
+		token  string
+		ok     bool
+	}{
+		{"Bearer abc", "abc", true},
+		{"bearer abc", "abc", true},
+		{"Bearer", "", false},
+		{"Bearer ", "", false},
+		{"Bea", "", false},
+		{"Basic abc", "", false},
+	}
+
+	for _, tc := range cases {
+		if token, ok := BearerToken(tc.header); token != tc.token || ok != tc.ok {
+			t.Errorf("BearerToken(%q) = %q, %v", tc.header, token, ok)
+		}
+	}
+}
+
+func TestUserFrom(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if _, ok := UserFrom(c); ok {
+		t.Fatal("got a user from an empty context")
+	}
+
+	SetUser(c, &CurrentUser{ID: 1, Scopes: []string{types.ScopeAccount}})
+
+	if u, ok := UserFrom(c); !ok || u.ID != 1 || !u.HasScope(types.ScopeAccount) || u.HasScope(types.ScopeArticlesWrite) {
+		t.Fatalf("got %+v, %v", u, ok)
+	}
+}
+
+func TestParseSessionToken(t *testing.T) {
+	k, err := keys.GenerateEd25519("test", keys.StateActive)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := keys.NewKeySet(k)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := types.JWTClaims{ID: 1, Username: "test", StandardClaims: jwt.StandardClaims{Id: "session", Issuer: types.TokenIssuer}}
+
+	cases := []struct {
+		name   string
+		mutate func(c *types.JWTClaims)
+		ok     bool
+	}{
+		{"valid", func(c *types.JWTClaims) {}, true},
+		{"issuer", func(c *types.JWTClaims) { c.Issuer = "Other" }, false},
+		{"audience", func(c *types.JWTClaims) { c.Audience = types.ChallengeAudience }, false},
+		{"session", func(c *types.JWTClaims) { c.Id = "" }, false},
+		{"user", func(c *types.JWTClaims) { c.ID = 0 }, false},
+		{"expired", func(c *types.JWTClaims) { c.ExpiresAt = 1 }, false},
+	}
+
+	for _, tc := range cases {
+		claims := valid
+		tc.mutate(&claims)
+		ss, err := ks.Sign(claims)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ParseSessionToken(ks, ss); (err == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, valid)
+	hs.Header["kid"] = "test"
+	ss, _ := hs.SignedString([]byte("secret"))
+
+	if _, err := ParseSessionToken(ks, ss); err == nil {
+		t.Error("accepted a token signed with an unexpected algorithm")
+	}
+}
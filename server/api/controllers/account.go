@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/throttle"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/api/validation"
+)
+
+var slugDisallowed = regexp.MustCompile("[^a-z0-9]+")
+
+func DeleteMe(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	policy := c.MustGet("deletionPolicy").(models.DeletionPolicy)
+	userID := auth.MustUser(c).ID
+
+	if c.Request.Body == nil {
+		c.Error(apierr.Field("password", "Password is required."))
+		return
+	}
+
+	body := &types.DeleteMeRequestBody{}
+
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u, err := store.GetUserByID(userID)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !reauthenticate(c, store, u, "password", body.Password) {
+		return
+	}
+
+	if policy == models.DeletionPolicyAnonymize {
+		err = store.AnonymizeUser(userID)
+	} else {
+		err = store.DeleteUser(userID)
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(204)
+}
+
+// reauthenticate confirms a sensitive change with the account's password or,
+// for accounts without one, a recent sign in. On failure it records the error
+// on c and returns false.
+func reauthenticate(c *gin.Context, store models.Store, u *models.User, field, pw string) bool {
+	if !u.PasswordSet {
+		lifetimes := c.MustGet("tokenLifetimes").(types.TokenLifetimes)
+		session, err := store.GetSession(auth.MustUser(c).SessionID)
+
+		if err != nil && err != models.ErrNotFound {
+			c.Error(err)
+			return false
+		}
+
+		if err == models.ErrNotFound || time.Since(session.CreatedAt) > lifetimes.Reauthentication {
+			c.Error(apierr.Forbidden("Sign in again to confirm this change."))
+			return false
+		}
+
+		return true
+	}
+
+	if pw == "" {
+		c.Error(validation.Required(field))
+		return false
+	}
+
+	policy := c.MustGet("passwordPolicy").(*password.Policy)
+	t := c.MustGet("throttle").(*throttle.Signin)
+	ip := c.ClientIP()
+	account := strings.ToLower(u.Username)
+
+	if d := t.Blocked(ip, account); d > 0 {
+		tooManySigninAttempts(c, d)
+		return false
+	}
+
+	if err := policy.Compare(u.Password, pw); err != nil {
+		t.Failure(ip, account)
+		c.Error(apierr.Field(field, "Password is invalid."))
+		return false
+	}
+
+	return true
+}
+
+func GetExport(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+
+	export, err := loadExport(store, userID)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	archive, err := writeExportArchive(export)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	filename := fmt.Sprintf("composition-%s-%s.zip", export.Profile.Username, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(200, "application/zip", archive)
+}
+
+func loadExport(store models.Store, userID int) (*types.Export, error) {
+	export := &types.Export{
+		ExportedAt: time.Now().UTC(),
+		Articles:   []types.ExportArticle{},
+		Identities: []types.ExportIdentity{},
+	}
+
+	u, err := store.GetUserByID(userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	export.Profile = types.ExportProfile{
+		ID:       u.ID,
+		Username: u.Username,
+		Email:    u.Email,
+	}
+
+	articles, err := store.GetArticlesByUser(userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range articles {
+		export.Articles = append(export.Articles, types.ExportArticle{
+			ID:        a.ID,
+			Title:     a.Title,
+			Body:      a.Body,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+
+	identities, err := store.GetIdentities(userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range identities {
+		export.Identities = append(export.Identities, types.ExportIdentity{
+			Provider:  i.Provider,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+
+	if export.Sessions, err = loadSessions(store, userID, ""); err != nil {
+		return nil, err
+	}
+
+	if export.APIKeys, err = loadAPIKeys(store, userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func writeExportArchive(export *types.Export) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	b, err := json.MarshalIndent(export, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeZipFile(zw, "export.json", export.ExportedAt, b); err != nil {
+		return nil, err
+	}
+
+	for _, a := range export.Articles {
+		name := fmt.Sprintf("articles/%d-%s.md", a.ID, slugify(a.Title))
+		md := fmt.Sprintf("# %s\n\n_Published %s_\n\n%s\n", a.Title, a.CreatedAt.Format("January 2, 2006"), a.Body)
+
+		if err := writeZipFile(zw, name, a.CreatedAt, []byte(md)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, b []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func slugify(title string) string {
+	slug := strings.Trim(slugDisallowed.ReplaceAllString(strings.ToLower(title), "-"), "-")
+
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+
+	if slug == "" {
+		return "untitled"
+	}
+
+	return slug
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/apikey"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/api/validation"
+)
+
+func DeleteAPIKey(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	err := store.DeleteAPIKey(id, userID)
+
+	if err == models.ErrNotFound {
+		c.Error(apierr.NotFound("Unable to find API key."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(204)
+}
+
+func GetAPIKeys(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+
+	apiKeys, err := loadAPIKeys(store, userID)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"api_keys": apiKeys})
+}
+
+func PostAPIKeys(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+
+	if c.Request.Body == nil {
+		c.Error(apierr.Validation("Name and scopes are required."))
+		return
+	}
+
+	body := &types.PostAPIKeysRequestBody{}
+
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !isAPIKeyScope(scope) {
+			c.Error(apierr.Field("scopes", "Scope \""+scope+"\" is invalid."))
+			return
+		}
+	}
+
+	if body.ExpiresAt != nil && body.ExpiresAt.Before(time.Now()) {
+		c.Error(apierr.Field("expires_at", "Expiration must be in the future."))
+		return
+	}
+
+	key, prefix, err := apikey.Generate()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	k := &models.APIKey{
+		UserID:    userID,
+		Name:      body.Name,
+		Prefix:    prefix,
+		KeyHash:   apikey.Hash(key),
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+	}
+
+	if err := store.CreateAPIKey(k); err != nil {
+		c.Error(err)
+		return
+	}
+
+	r := types.PostAPIKeysResponseBody{
+		APIKey: types.APIKey{
+			ID:        k.ID,
+			Name:      body.Name,
+			Prefix:    prefix,
+			Scopes:    body.Scopes,
+			CreatedAt: k.CreatedAt,
+			ExpiresAt: body.ExpiresAt,
+		},
+		Key: key,
+	}
+
+	c.JSON(201, r)
+}
+
+func loadAPIKeys(store models.APIKeyStore, userID int) ([]types.APIKey, error) {
+	keys, err := store.GetAPIKeys(userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := []types.APIKey{}
+
+	for _, k := range keys {
+		apiKeys = append(apiKeys, types.APIKey{
+			ID:         k.ID,
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scopes:     k.Scopes,
+			CreatedAt:  k.CreatedAt,
+			LastUsedAt: k.LastUsedAt,
+			ExpiresAt:  k.ExpiresAt,
+		})
+	}
+
+	return apiKeys, nil
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, s := range types.APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -1,103 +1,86 @@
 package controllers
 
 import (
-	"database/sql"
 	"strconv"
-	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/metrics"
 	"github.com/richardpanda/composition/server/api/models"
 	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/api/validation"
 )
 
 func GetArticle(c *gin.Context) {
-	db := c.MustGet("db").(*sql.DB)
+	store := c.MustGet("store").(models.Store)
 	id, _ := strconv.Atoi(c.Param("id"))
 
-	var (
-		articleID int
-		title     string
-		body      string
-		username  string
-		createdAt time.Time
-	)
+	a, err := store.GetArticle(id)
 
-	err := models.GetArticle(db, id).Scan(&articleID, &title, &body, &username, &createdAt)
-
-	if err == sql.ErrNoRows {
-		c.JSON(404, gin.H{"message": "Unable to find article."})
+	if err == models.ErrNotFound {
+		c.Error(apierr.NotFound("Unable to find article."))
 		return
 	}
 
 	if err != nil {
-		c.JSON(400, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	r := types.GetArticleResponseBody{
-		ID:        articleID,
-		Title:     title,
-		Body:      body,
-		Username:  username,
-		CreatedAt: createdAt,
+		ID:        a.ID,
+		Title:     a.Title,
+		Body:      a.Body,
+		Username:  a.Username,
+		CreatedAt: a.CreatedAt,
+	}
+
+	if user, ok := auth.UserFrom(c); ok {
+		r.IsAuthor = user.ID == a.UserID
 	}
 
 	c.JSON(200, r)
 }
 
 func GetArticles(c *gin.Context) {
-	db := c.MustGet("db").(*sql.DB)
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	store := c.MustGet("store").(models.Store)
+	query := &types.GetArticlesQuery{Page: 1}
+
+	if err := validation.BindQuery(c, query); err != nil {
+		c.Error(err)
+		return
+	}
 
-	rows, err := models.GetLatestArticlePreviews(db, page)
+	articles, err := store.GetArticlePreviews(query.Page)
 
 	if err != nil {
-		c.JSON(500, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	defer rows.Close()
-
 	articlePreviews := []types.ArticlePreview{}
 
-	for rows.Next() {
-		var (
-			username, title string
-			id              int
-			createdAt       time.Time
-		)
-
-		if err := rows.Scan(&username, &title, &id, &createdAt); err != nil {
-			c.JSON(500, gin.H{"message": err.Error()})
-			return
-		}
-
-		articlePreviews = append(articlePreviews, types.ArticlePreview{username, title, id, createdAt})
+	for _, a := range articles {
+		articlePreviews = append(articlePreviews, types.ArticlePreview{
+			Username:  a.Username,
+			Title:     a.Title,
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+		})
 	}
 
 	c.JSON(200, gin.H{"article_previews": articlePreviews})
 }
 
 func PostArticles(c *gin.Context) {
-	db := c.MustGet("db").(*sql.DB)
-	user, _ := c.Get("user")
-	userID := int(user.(jwt.MapClaims)["id"].(float64))
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
 
 	body := &types.PostArticlesRequestBody{}
 
-	if err := c.BindJSON(body); err != nil {
-		c.JSON(400, gin.H{"message": err.Error()})
-		return
-	}
-
-	if body.Title == "" {
-		c.JSON(400, gin.H{"message": "Title is required."})
-		return
-	}
-
-	if body.Body == "" {
-		c.JSON(400, gin.H{"message": "Body is required."})
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -107,8 +90,14 @@ func PostArticles(c *gin.Context) {
 		Body:   body.Body,
 	}
 
-	var id int
-	_ = models.CreateArticle(db, a).Scan(&id)
+	id, err := store.CreateArticle(a)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.MustGet("metrics").(*metrics.Metrics).ArticleCreated()
 
 	c.JSON(201, gin.H{"article_id": id, "title": body.Title, "body": body.Body})
 }
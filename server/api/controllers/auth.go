@@ -1,139 +1,202 @@
 package controllers
 
 import (
-	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/logging"
+	"github.com/richardpanda/composition/server/api/metrics"
 	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/throttle"
 	"github.com/richardpanda/composition/server/api/types"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/richardpanda/composition/server/api/validation"
 )
 
+const invalidCredentialsMessage = "Username or password is invalid."
+
+var errNoPassword = errors.New("account has no password")
+
 func PostSignin(c *gin.Context) {
-	db := c.MustGet("db").(*sql.DB)
+	store := c.MustGet("store").(models.Store)
+	policy := c.MustGet("passwordPolicy").(*password.Policy)
+	m := c.MustGet("metrics").(*metrics.Metrics)
 
 	if c.Request.Body == nil {
-		c.JSON(400, gin.H{"message": "Username and password are required."})
+		c.Error(apierr.Validation("Username and password are required."))
 		return
 	}
 
 	body := &types.SigninRequestBody{}
 
-	if err := c.BindJSON(body); err != nil {
-		c.JSON(400, gin.H{"message": err.Error()})
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	t := c.MustGet("throttle").(*throttle.Signin)
+	ip := c.ClientIP()
+	account := strings.ToLower(body.Username)
+
+	if d := t.Blocked(ip, account); d > 0 {
+		m.Signin("password", "throttled")
+		tooManySigninAttempts(c, d)
 		return
 	}
 
-	var (
-		id       int
-		username string
-		email    string
-		password string
-	)
+	u, err := store.GetUserByUsername(body.Username)
 
-	err := models.GetUserByUsername(db, body.Username).Scan(&id, &username, &email, &password)
+	if err == models.ErrNotFound {
+		policy.CompareDummy(body.Password)
+		t.Failure(ip, account)
+		m.Signin("password", "failure")
+		c.Error(apierr.Unauthorized(invalidCredentialsMessage))
+		return
+	}
 
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Username is invalid."})
+		c.Error(err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(password), []byte(body.Password))
+	if u.PasswordSet {
+		err = policy.Compare(u.Password, body.Password)
+	} else {
+		policy.CompareDummy(body.Password)
+		err = errNoPassword
+	}
 
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Password is invalid."})
+		t.Failure(ip, account)
+		m.Signin("password", "failure")
+		c.Error(apierr.Unauthorized(invalidCredentialsMessage))
 		return
 	}
 
-	claims := types.JWTClaims{
-		ID:       id,
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			Issuer: "Composition",
-		},
-	}
+	m.Signin("password", "success")
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, _ := token.SignedString(types.JWTSecret)
+	if policy.NeedsRehash(u.Password) {
+		rehashed, err := policy.Hash(body.Password)
 
-	c.JSON(200, gin.H{"token": ss})
+		if err == nil {
+			err = store.UpdateUserPassword(u.ID, rehashed)
+		}
+
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn().Err(err).Int("user_id", u.ID).Msg("rehashing password")
+		}
+	}
+
+	// With two-factor authentication the lockout must keep counting failed
+	// codes, so it is only reset once a session token is issued.
+	if completeSignin(c, store, u.ID, u.Username) {
+		t.Success(account)
+	}
 }
 
 func PostSignup(c *gin.Context) {
-	db := c.MustGet("db").(*sql.DB)
+	store := c.MustGet("store").(models.Store)
+	policy := c.MustGet("passwordPolicy").(*password.Policy)
 
 	if c.Request.Body == nil {
-		c.JSON(400, gin.H{"message": "Username, email, password, and password confirm are required."})
+		c.Error(apierr.Validation("Username, email, password, and password confirm are required."))
 		return
 	}
 
 	body := &types.SignupRequestBody{}
 
-	if err := c.BindJSON(body); err != nil {
-		c.JSON(400, gin.H{"message": err.Error()})
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if body.Username == "" {
-		c.JSON(400, gin.H{"message": "Username is required."})
+	if err := policy.Validate(body.Password, body.Username, body.Email); err != nil {
+		c.Error(apierr.Field("password", err.Error()))
 		return
 	}
 
-	if body.Email == "" {
-		c.JSON(400, gin.H{"message": "Email is required."})
+	hash, err := policy.Hash(body.Password)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	u := &models.User{
+		Username: body.Username,
+		Email:    body.Email,
+		Password: hash,
+	}
+
+	id, err := store.CreateUser(u)
+
+	if err == models.ErrUsernameTaken {
+		c.Error(apierr.Conflict("Username is not available."))
 		return
 	}
 
-	if body.Password == "" {
-		c.JSON(400, gin.H{"message": "Password is required."})
+	if err == models.ErrEmailTaken {
+		c.Error(apierr.Conflict("Email is not available."))
 		return
 	}
 
-	if body.PasswordConfirm == "" {
-		c.JSON(400, gin.H{"message": "Password confirm is required."})
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if body.Password != body.PasswordConfirm {
-		c.JSON(400, gin.H{"message": "Passwords do not match."})
+	c.MustGet("metrics").(*metrics.Metrics).Signup("password")
+
+	ss, err := newToken(c, id, body.Username)
+
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	hash, _ := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.MinCost)
+	c.JSON(200, gin.H{"token": ss})
+}
 
-	u := &models.User{
-		Username: body.Username,
-		Email:    body.Email,
-		Password: string(hash),
+func tooManySigninAttempts(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	c.Error(apierr.TooManyRequests("Too many failed signin attempts. Try again later."))
+}
+
+// completeSignin responds with a session token, or with a challenge token
+// when two-factor authentication is enabled. It reports whether a session
+// token was issued.
+func completeSignin(c *gin.Context, store models.TwoFactorStore, id int, username string) bool {
+	twoFactor, err := store.GetTwoFactor(id)
+
+	if err != nil && err != models.ErrNotFound {
+		c.Error(err)
+		return false
 	}
 
-	var id int
-	err := models.CreateUser(db, u).Scan(&id)
+	if err == nil && twoFactor.Enabled {
+		ss, err := newChallengeToken(c, id, username)
 
-	if err, ok := err.(*pq.Error); ok {
-		switch err.Error() {
-		case "pq: duplicate key value violates unique constraint \"users_username_key\"":
-			c.JSON(400, gin.H{"message": "Username is not available."})
-		case "pq: duplicate key value violates unique constraint \"users_email_key\"":
-			c.JSON(400, gin.H{"message": "Email is not available."})
-		default:
-			c.JSON(500, gin.H{"message": err.Error()})
+		if err != nil {
+			c.Error(err)
+			return false
 		}
-		return
-	}
 
-	claims := types.JWTClaims{
-		ID:       id,
-		Username: body.Username,
-		StandardClaims: jwt.StandardClaims{
-			Issuer: "Composition",
-		},
+		c.JSON(200, gin.H{"challenge_token": ss})
+		return false
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, _ := token.SignedString(types.JWTSecret)
+	ss, err := newToken(c, id, username)
+
+	if err != nil {
+		c.Error(err)
+		return false
+	}
 
 	c.JSON(200, gin.H{"token": ss})
+	return true
 }
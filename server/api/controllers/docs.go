@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/openapi"
+)
+
+func GetDocs(c *gin.Context) {
+	c.Data(200, "text/html; charset=utf-8", openapi.Docs)
+}
+
+func GetOpenAPI(c *gin.Context) {
+	c.Data(200, "application/json; charset=utf-8", openapi.Spec)
+}
@@ -0,0 +1,10 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+)
+
+func NoRoute(c *gin.Context) {
+	c.Error(apierr.NotFound("Unable to find route."))
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/health"
+	"github.com/richardpanda/composition/server/api/logging"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func GetHealthz(c *gin.Context) {
+	c.JSON(200, types.HealthResponseBody{Status: "ok"})
+}
+
+func GetReadyz(c *gin.Context) {
+	h := c.MustGet("health").(*health.Checker)
+	body := types.ReadinessResponseBody{Status: "ok", Checks: map[string]types.CheckResult{}}
+
+	for _, r := range h.Run(c.Request.Context()) {
+		result := types.CheckResult{
+			Status:     "ok",
+			DurationMS: float64(r.Duration.Microseconds()) / 1000,
+		}
+
+		if r.Err != nil {
+			// The endpoint is public, so the cause only goes to the log.
+			logging.FromContext(c.Request.Context()).Warn().Err(r.Err).Str("check", r.Name).Msg("readiness check failed")
+			result.Status = "error"
+
+			if !r.Optional {
+				body.Status = "unavailable"
+			} else if body.Status == "ok" {
+				body.Status = "degraded"
+			}
+		}
+
+		body.Checks[r.Name] = result
+	}
+
+	code := 200
+
+	if body.Status == "unavailable" {
+		code = 503
+	}
+
+	c.JSON(code, body)
+}
@@ -0,0 +1,11 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/keys"
+)
+
+func GetJWKS(c *gin.Context) {
+	ks := c.MustGet("keys").(*keys.KeySet)
+	c.JSON(200, ks.JWKS())
+}
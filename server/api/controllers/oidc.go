@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/metrics"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// The state cookie is scoped to every API version, since a sign in may start
+// under /api and finish under /api/v1, and named per provider.
+const oidcStatePath = "/api"
+
+func oidcStateCookie(provider string) string {
+	return "oidc_state_" + provider
+}
+
+var usernameDisallowed = regexp.MustCompile("[^a-z0-9_]+")
+
+func GetAuthCallback(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	ks := c.MustGet("keys").(*keys.KeySet)
+	providers := c.MustGet("oidc").(oidc.Providers)
+	m := c.MustGet("metrics").(*metrics.Metrics)
+	name := c.Param("provider")
+	p, ok := providers[name]
+
+	if !ok {
+		c.Error(apierr.NotFound("Unable to find provider."))
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie(name))
+	c.SetCookie(oidcStateCookie(name), "", -1, oidcStatePath, "", c.Request.TLS != nil, true)
+
+	if err != nil {
+		c.Error(apierr.BadRequest("Sign in state is missing or expired."))
+		return
+	}
+
+	if e := c.Query("error"); e != "" {
+		c.Error(apierr.BadRequest(fmt.Sprintf("Provider returned an error: %s.", e)))
+		return
+	}
+
+	state := &types.OIDCStateClaims{}
+	t, err := jwt.ParseWithClaims(cookie, state, ks.Keyfunc)
+
+	if err != nil || !t.Valid || !state.VerifyAudience(types.OIDCStateAudience, true) ||
+		state.Provider != name || state.State == "" || state.State != c.Query("state") {
+		c.Error(apierr.BadRequest("Sign in state is missing or expired."))
+		return
+	}
+
+	rawIDToken, err := p.Exchange(c.Query("code"), state.Verifier)
+
+	if err != nil {
+		c.Error(apierr.Upstream("Unable to complete sign in with provider.", err))
+		return
+	}
+
+	identity, err := p.VerifyIDToken(rawIDToken, state.Nonce)
+
+	if err != nil {
+		c.Error(apierr.Unauthorized("Provider returned an invalid identity."))
+		return
+	}
+
+	u, err := store.GetUserByIdentity(name, identity.Subject)
+
+	if err == nil && state.UserID != 0 && u.ID != state.UserID {
+		c.Error(apierr.Conflict("Identity is linked to another account."))
+		return
+	}
+
+	if err == models.ErrNotFound {
+		u, err = linkIdentity(store, m, name, identity, state.UserID)
+
+		if err == errEmailUnavailable {
+			c.Error(apierr.Conflict("Email is not available."))
+			return
+		}
+
+		if err == errEmailRequired {
+			c.Error(apierr.BadRequest("Provider did not supply an email address."))
+			return
+		}
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	m.Signin("oidc", "success")
+	completeSignin(c, store, u.ID, u.Username)
+}
+
+func GetAuthStart(c *gin.Context) {
+	ks := c.MustGet("keys").(*keys.KeySet)
+	providers := c.MustGet("oidc").(oidc.Providers)
+	lifetimes := c.MustGet("tokenLifetimes").(types.TokenLifetimes)
+	name := c.Param("provider")
+	p, ok := providers[name]
+
+	if !ok {
+		c.Error(apierr.NotFound("Unable to find provider."))
+		return
+	}
+
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+
+	for _, err := range []error{err1, err2, err3} {
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	u, err := p.AuthCodeURL(state, nonce, verifier)
+
+	if err != nil {
+		c.Error(apierr.Upstream("Unable to reach provider.", err))
+		return
+	}
+
+	claims := types.OIDCStateClaims{
+		Provider: name,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  types.OIDCStateAudience,
+			ExpiresAt: time.Now().Add(lifetimes.OIDCState).Unix(),
+			Issuer:    types.TokenIssuer,
+		},
+	}
+
+	if user, ok := auth.UserFrom(c); ok {
+		if !user.HasScope(types.ScopeAccount) {
+			c.Error(apierr.Forbidden("Insufficient scope."))
+			return
+		}
+
+		claims.UserID = user.ID
+	}
+
+	ss, err := ks.Sign(claims)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie(name), ss, int(lifetimes.OIDCState.Seconds()), oidcStatePath, "", c.Request.TLS != nil, true)
+	c.Redirect(302, u)
+}
+
+var (
+	errEmailRequired    = errors.New("email required")
+	errEmailUnavailable = errors.New("email unavailable")
+)
+
+// linkIdentity connects a new provider identity to the user with userID, who
+// started the sign in from a session, or to a new account. An existing
+// account is never linked by email alone: local signup does not verify email,
+// so whoever registered the address first could take over the identity.
+func linkIdentity(store models.Store, m *metrics.Metrics, provider string, identity *oidc.Identity, userID int) (*models.User, error) {
+	var u *models.User
+	var err error
+
+	if userID != 0 {
+		u, err = store.GetUserByID(userID)
+	} else if identity.Email == "" {
+		return nil, errEmailRequired
+	} else if _, err = store.GetUserByEmail(identity.Email); err == nil {
+		return nil, errEmailUnavailable
+	} else if err == models.ErrNotFound {
+		if u, err = createOIDCUser(store, identity); err == nil {
+			m.Signup("oidc")
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := &models.Identity{
+		UserID:   u.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	if err := store.CreateIdentity(i); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// createOIDCUser creates an account without a password. The user can set one
+// later after signing in.
+func createOIDCUser(store models.UserStore, identity *oidc.Identity) (*models.User, error) {
+	base := identity.PreferredUsername
+
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	base = usernameDisallowed.ReplaceAllString(strings.ToLower(base), "")
+
+	if base == "" {
+		base = "user"
+	}
+
+	if len(base) > 15 {
+		base = base[:15]
+	}
+
+	for i := 0; i < 10; i++ {
+		username := base
+
+		if i > 0 {
+			username = fmt.Sprintf("%s%d", base, i+1)
+		}
+
+		_, err := store.GetUserByUsername(username)
+
+		if err == nil {
+			continue
+		}
+
+		if err != models.ErrNotFound {
+			return nil, err
+		}
+
+		u := &models.User{
+			Username: username,
+			Email:    identity.Email,
+		}
+
+		u.ID, err = store.CreateUser(u)
+
+		if err == models.ErrUsernameTaken {
+			continue
+		}
+
+		if err == models.ErrEmailTaken {
+			return nil, errEmailUnavailable
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return u, nil
+	}
+
+	return nil, fmt.Errorf("unable to choose a username for %q", identity.Email)
+}
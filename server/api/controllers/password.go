@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/api/validation"
+)
+
+func PostPassword(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	policy := c.MustGet("passwordPolicy").(*password.Policy)
+	user := auth.MustUser(c)
+
+	if c.Request.Body == nil {
+		c.Error(apierr.Validation("Current password, new password, and new password confirm are required."))
+		return
+	}
+
+	body := &types.ChangePasswordRequestBody{}
+
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u, err := store.GetUserByID(user.ID)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !reauthenticate(c, store, u, "current_password", body.CurrentPassword) {
+		return
+	}
+
+	if err := policy.Validate(body.NewPassword, u.Username, u.Email); err != nil {
+		c.Error(apierr.Field("new_password", err.Error()))
+		return
+	}
+
+	hash, err := policy.Hash(body.NewPassword)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := store.UpdateUserPassword(user.ID, hash); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := store.RevokeOtherSessions(user.ID, user.SessionID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(204)
+}
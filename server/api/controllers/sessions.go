@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func DeleteSession(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+
+	err := store.RevokeSession(c.Param("id"), userID)
+
+	if err == models.ErrNotFound {
+		c.Error(apierr.NotFound("Unable to find session."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(204)
+}
+
+func GetSessions(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	user := auth.MustUser(c)
+
+	sessions, err := loadSessions(store, user.ID, user.SessionID)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"sessions": sessions})
+}
+
+// PostToken reissues the current session's token with a new expiry. The
+// session keeps its ID and sign in time, so revoking it still ends every token
+// issued for it and reauthentication is not extended.
+func PostToken(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	user := auth.MustUser(c)
+	expiresAt := sessionExpiry(c)
+	err := store.ExtendSession(user.SessionID, user.ID, expiresAt)
+
+	if err == models.ErrNotFound {
+		c.Error(apierr.Unauthorized("Session has been revoked."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ss, err := signSessionToken(c, user.ID, user.Username, user.SessionID, expiresAt)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"token": ss})
+}
+
+func loadSessions(store models.SessionStore, userID int, current string) ([]types.Session, error) {
+	records, err := store.GetSessions(userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []types.Session{}
+
+	for _, s := range records {
+		sessions = append(sessions, types.Session{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.ID == current,
+		})
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func newToken(c *gin.Context, id int, username string) (string, error) {
+	store := c.MustGet("store").(models.Store)
+
+	sessionID, err := newTokenID()
+
+	if err != nil {
+		return "", err
+	}
+
+	s := &models.Session{
+		ID:        sessionID,
+		UserID:    id,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+		ExpiresAt: sessionExpiry(c),
+	}
+
+	if err := store.CreateSession(s); err != nil {
+		return "", err
+	}
+
+	return signSessionToken(c, id, username, s.ID, s.ExpiresAt)
+}
+
+// sessionExpiry is when a token issued now expires, or nil if session tokens
+// do not expire.
+func sessionExpiry(c *gin.Context) *time.Time {
+	l := c.MustGet("tokenLifetimes").(types.TokenLifetimes)
+
+	if l.Session <= 0 {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(l.Session)
+	return &expiresAt
+}
+
+func signSessionToken(c *gin.Context, id int, username, sessionID string, expiresAt *time.Time) (string, error) {
+	ks := c.MustGet("keys").(*keys.KeySet)
+
+	claims := types.JWTClaims{
+		ID:       id,
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			Id:     sessionID,
+			Issuer: types.TokenIssuer,
+		},
+	}
+
+	if expiresAt != nil {
+		claims.ExpiresAt = expiresAt.Unix()
+	}
+
+	return ks.Sign(claims)
+}
+
+// newChallengeToken records the challenge so that PostSigninTwoFactor can
+// accept it only once.
+func newChallengeToken(c *gin.Context, id int, username string) (string, error) {
+	store := c.MustGet("store").(models.Store)
+	ks := c.MustGet("keys").(*keys.KeySet)
+	lifetimes := c.MustGet("tokenLifetimes").(types.TokenLifetimes)
+
+	challengeID, err := newTokenID()
+
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(lifetimes.Challenge)
+
+	if err := store.CreateChallenge(challengeID, id, expiresAt); err != nil {
+		return "", err
+	}
+
+	claims := types.JWTClaims{
+		ID:       id,
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  types.ChallengeAudience,
+			ExpiresAt: expiresAt.Unix(),
+			Id:        challengeID,
+			Issuer:    types.TokenIssuer,
+		},
+	}
+
+	return ks.Sign(claims)
+}
+
+func parseChallengeToken(c *gin.Context, tokenString string) (*types.JWTClaims, error) {
+	ks := c.MustGet("keys").(*keys.KeySet)
+	claims := &types.JWTClaims{}
+
+	t, err := jwt.ParseWithClaims(tokenString, claims, ks.Keyfunc)
+
+	if err != nil || !t.Valid {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	if claims.Issuer != types.TokenIssuer || !claims.VerifyAudience(types.ChallengeAudience, true) || claims.ExpiresAt == 0 || claims.Id == "" {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	return claims, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/metrics"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/throttle"
+	"github.com/richardpanda/composition/server/api/totp"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/api/validation"
+)
+
+const recoveryCodeCount = 10
+
+func PostSigninTwoFactor(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+
+	if c.Request.Body == nil {
+		c.Error(apierr.Validation("Challenge token and code are required."))
+		return
+	}
+
+	body := &types.SigninTwoFactorRequestBody{}
+
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims, err := parseChallengeToken(c, body.ChallengeToken)
+
+	if err != nil {
+		c.Error(apierr.Unauthorized("Challenge token is invalid or expired."))
+		return
+	}
+
+	t := c.MustGet("throttle").(*throttle.Signin)
+	ip := c.ClientIP()
+	account := strings.ToLower(claims.Username)
+
+	m := c.MustGet("metrics").(*metrics.Metrics)
+
+	if d := t.Blocked(ip, account); d > 0 {
+		m.Signin("two_factor", "throttled")
+		tooManySigninAttempts(c, d)
+		return
+	}
+
+	twoFactor, err := store.GetTwoFactor(claims.ID)
+
+	if err != nil || !twoFactor.Enabled {
+		c.Error(apierr.Unauthorized("Challenge token is invalid or expired."))
+		return
+	}
+
+	step, _ := totp.Validate(body.Code, twoFactor.Secret, time.Now(), twoFactor.LastStep)
+	err = store.CompleteChallenge(claims.Id, claims.ID, step, hashRecoveryCode(body.Code))
+
+	if err == models.ErrChallengeUsed {
+		c.Error(apierr.Unauthorized("Challenge token is invalid or expired."))
+		return
+	}
+
+	if err == models.ErrNotFound {
+		t.Failure(ip, account)
+		m.Signin("two_factor", "failure")
+		c.Error(apierr.Unauthorized("Code is invalid."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	t.Success(account)
+	m.Signin("two_factor", "success")
+
+	ss, err := newToken(c, claims.ID, claims.Username)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"token": ss})
+}
+
+func PostTwoFactorConfirm(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	userID := auth.MustUser(c).ID
+
+	if c.Request.Body == nil {
+		c.Error(apierr.Field("code", "Code is required."))
+		return
+	}
+
+	body := &types.TwoFactorConfirmRequestBody{}
+
+	if err := validation.Bind(c, body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	twoFactor, err := store.GetTwoFactor(userID)
+
+	if err == models.ErrNotFound {
+		c.Error(apierr.Conflict("Two-factor authentication has not been set up."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if twoFactor.Enabled {
+		c.Error(apierr.Conflict("Two-factor authentication is already enabled."))
+		return
+	}
+
+	step, ok := totp.Validate(body.Code, twoFactor.Secret, time.Now(), twoFactor.LastStep)
+
+	if !ok {
+		c.Error(apierr.Field("code", "Code is invalid."))
+		return
+	}
+
+	err = store.UseTOTPStep(userID, step)
+
+	if err == models.ErrNotFound {
+		c.Error(apierr.Field("code", "Code is invalid."))
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	hashes := make([]string, len(codes))
+
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := store.EnableTwoFactor(userID, hashes); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"recovery_codes": codes})
+}
+
+func PostTwoFactorSetup(c *gin.Context) {
+	store := c.MustGet("store").(models.Store)
+	user := auth.MustUser(c)
+	userID, username := user.ID, user.Username
+
+	twoFactor, err := store.GetTwoFactor(userID)
+
+	if err != nil && err != models.ErrNotFound {
+		c.Error(err)
+		return
+	}
+
+	if err == nil && twoFactor.Enabled {
+		c.Error(apierr.Conflict("Two-factor authentication is already enabled."))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := store.SetTwoFactorSecret(userID, secret); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"secret": secret, "uri": totp.URI("Composition", username, secret)})
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 5)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		s := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = s[:4] + "-" + s[4:]
+	}
+
+	return codes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
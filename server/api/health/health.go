@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardpanda/composition/server/migrations"
+	"github.com/richardpanda/composition/server/workers"
+)
+
+type Check struct {
+	Name     string
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+type Result struct {
+	Check
+	Err      error
+	Duration time.Duration
+}
+
+type Checker struct {
+	Timeout time.Duration
+	Checks  []Check
+}
+
+func (h *Checker) Run(ctx context.Context) []Result {
+	if h == nil {
+		return nil
+	}
+
+	results := make([]Result, len(h.Checks))
+	var wg sync.WaitGroup
+
+	for i, check := range h.Checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			errs := make(chan error, 1)
+			go func() { errs <- check.Run(ctx) }()
+
+			var err error
+
+			select {
+			case err = <-errs:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			results[i] = Result{Check: check, Err: err, Duration: time.Since(start)}
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func Database(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Run:  db.PingContext,
+	}
+}
+
+func Migrations(m *migrations.Migrator) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			pending, err := m.Pending(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending, next is %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+			}
+
+			return nil
+		},
+	}
+}
+
+func Workers(g *workers.Group) Check {
+	return Check{
+		Name:     "workers",
+		Optional: true,
+		Run: func(ctx context.Context) error {
+			var problems []string
+
+			for _, s := range g.Status() {
+				if !s.Running {
+					problems = append(problems, s.Name+" is not running")
+				} else if s.LastError != "" {
+					problems = append(problems, s.Name+": "+s.LastError)
+				}
+			}
+
+			if len(problems) > 0 {
+				return fmt.Errorf("%s", strings.Join(problems, "; "))
+			}
+
+			return nil
+		},
+	}
+}
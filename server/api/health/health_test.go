@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	h := &Checker{
+		Timeout: 20 * time.Millisecond,
+		Checks: []Check{
+			{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+			{Name: "failing", Run: func(ctx context.Context) error { return errors.New("boom") }},
+			{Name: "slow", Run: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}},
+		},
+	}
+
+	start := time.Now()
+	results := h.Run(context.Background())
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("slow check was not cut off by the timeout")
+	}
+
+	if len(results) != 3 || results[0].Err != nil || results[1].Err.Error() != "boom" || results[2].Err != context.DeadlineExceeded {
+		t.Fatalf("got %+v", results)
+	}
+}
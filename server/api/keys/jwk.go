@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func ParseJWK(jwk types.JWK) (crypto.PublicKey, jwt.SigningMethod, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		method := jwt.GetSigningMethod(jwk.Alg)
+		if jwk.Alg == "" {
+			method = jwt.SigningMethodRS256
+		}
+
+		if _, ok := method.(*jwt.SigningMethodRSA); !ok {
+			return nil, nil, fmt.Errorf("keys: JWK %q has unsupported alg %q", jwk.Kid, jwk.Alg)
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, method, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, nil, fmt.Errorf("keys: JWK %q has unsupported curve %q", jwk.Kid, jwk.Crv)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, jwt.SigningMethodES256, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, nil, fmt.Errorf("keys: JWK %q has unsupported curve %q", jwk.Kid, jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("keys: JWK %q has an invalid Ed25519 key", jwk.Kid)
+		}
+
+		return ed25519.PublicKey(x), SigningMethodEdDSA, nil
+	}
+
+	return nil, nil, fmt.Errorf("keys: JWK %q has unsupported kty %q", jwk.Kid, jwk.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("keys: invalid JWK parameter %q", s)
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
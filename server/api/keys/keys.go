@@ -0,0 +1,255 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+const (
+	StateActive   = "active"
+	StateRetiring = "retiring"
+)
+
+type Key struct {
+	ID      string
+	State   string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	order  []string
+}
+
+type manifest struct {
+	Keys []struct {
+		ID    string `json:"kid"`
+		File  string `json:"file"`
+		State string `json:"state"`
+	} `json:"keys"`
+}
+
+func Load(path string) (*KeySet, error) {
+	if path == "" {
+		return nil, errors.New("keys: no keyset configured")
+	}
+
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("keys: %s: %v", path, err)
+	}
+
+	var ks []*Key
+
+	for _, entry := range m.Keys {
+		file := entry.File
+
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+
+		pemBytes, err := os.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		k, err := ParsePEM(entry.ID, entry.State, pemBytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ks = append(ks, k)
+	}
+
+	return NewKeySet(ks...)
+}
+
+func NewKeySet(ks ...*Key) (*KeySet, error) {
+	set := &KeySet{keys: map[string]*Key{}}
+
+	for _, k := range ks {
+		if k.ID == "" {
+			return nil, errors.New("keys: key is missing a kid")
+		}
+
+		if _, ok := set.keys[k.ID]; ok {
+			return nil, fmt.Errorf("keys: duplicate kid %q", k.ID)
+		}
+
+		switch k.State {
+		case StateActive:
+			if set.active != nil {
+				return nil, errors.New("keys: more than one active key")
+			}
+
+			if k.Private == nil {
+				return nil, fmt.Errorf("keys: active key %q has no private key", k.ID)
+			}
+
+			set.active = k
+		case StateRetiring:
+		default:
+			return nil, fmt.Errorf("keys: key %q has unknown state %q", k.ID, k.State)
+		}
+
+		set.keys[k.ID] = k
+		set.order = append(set.order, k.ID)
+	}
+
+	if set.active == nil {
+		return nil, errors.New("keys: no active key")
+	}
+
+	return set, nil
+}
+
+func ParsePEM(id, state string, pemBytes []byte) (*Key, error) {
+	block, _ := pem.Decode(pemBytes)
+
+	if block == nil {
+		return nil, fmt.Errorf("keys: key %q is not PEM encoded", id)
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("keys: key %q has unsupported PEM type %q", id, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("keys: key %q: %v", id, err)
+	}
+
+	return newKey(id, state, parsed)
+}
+
+func GenerateEd25519(id, state string) (*Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(id, state, private)
+}
+
+func GenerateRSA(id, state string) (*Key, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(id, state, private)
+}
+
+func newKey(id, state string, parsed interface{}) (*Key, error) {
+	k := &Key{ID: id, State: state}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.Private, k.Public = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.Public = jwt.SigningMethodRS256, key
+	case ed25519.PrivateKey:
+		k.Method, k.Private, k.Public = SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.Public = SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("keys: key %q must be RSA or Ed25519", id)
+	}
+
+	if k.Method == jwt.SigningMethodRS256 && k.Public.(*rsa.PublicKey).N.BitLen() < 2048 {
+		return nil, fmt.Errorf("keys: RSA key %q must be at least 2048 bits", id)
+	}
+
+	return k, nil
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.Private)
+}
+
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+
+	if !ok {
+		return nil, fmt.Errorf("keys: unknown kid %q", kid)
+	}
+
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("keys: unexpected signing method %q", token.Method.Alg())
+	}
+
+	return k.Public, nil
+}
+
+func (ks *KeySet) JWKS() types.JWKSResponseBody {
+	body := types.JWKSResponseBody{Keys: []types.JWK{}}
+
+	for _, kid := range ks.order {
+		body.Keys = append(body.Keys, toJWK(ks.keys[kid]))
+	}
+
+	return body
+}
+
+func toJWK(k *Key) types.JWK {
+	jwk := types.JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
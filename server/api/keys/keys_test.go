@@ -0,0 +1,75 @@
+package keys
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKey(t *testing.T, dir, name string, k *Key) {
+	b, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})
+	if err := os.WriteFile(filepath.Join(dir, name), p, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := os.MkdirTemp("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	active, _ := GenerateEd25519("new", StateActive)
+	retiring, _ := GenerateRSA("old", StateRetiring)
+	writeKey(t, dir, "new.pem", active)
+	writeKey(t, dir, "old.pem", retiring)
+
+	manifest := `{"keys": [
+		{"kid": "new", "file": "new.pem", "state": "active"},
+		{"kid": "old", "file": "old.pem", "state": "retiring"}
+	]}`
+	path := filepath.Join(dir, "keyset.json")
+	if err := os.WriteFile(path, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ks.active.ID != "new" || ks.active.Method != SigningMethodEdDSA {
+		t.Fatalf("unexpected active key %q", ks.active.ID)
+	}
+
+	if jwks := ks.JWKS(); len(jwks.Keys) != 2 || jwks.Keys[1].Kty != "RSA" {
+		t.Fatalf("unexpected JWKS %+v", jwks)
+	}
+}
+
+func TestLoadWithoutKeyset(t *testing.T) {
+	if _, err := Load(""); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNewKeySetRequiresOneActiveKey(t *testing.T) {
+	retiring, _ := GenerateEd25519("old", StateRetiring)
+	if _, err := NewKeySet(retiring); err == nil {
+		t.Fatal("expected an error without an active key")
+	}
+
+	a, _ := GenerateEd25519("a", StateActive)
+	b, _ := GenerateEd25519("b", StateActive)
+	if _, err := NewKeySet(a, b); err == nil {
+		t.Fatal("expected an error with two active keys")
+	}
+}
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const redacted = "[REDACTED]"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+var sensitiveParams = []string{"password", "secret", "token", "code", "key", "state"}
+
+func New(w io.Writer, level string) (zerolog.Logger, error) {
+	lvl, err := zerolog.ParseLevel(level)
+
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+
+	return zerolog.New(w).Level(lvl).With().Timestamp().Logger(), nil
+}
+
+func FromContext(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(ctx)
+}
+
+func Middleware(base zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Set("requestID", id)
+
+		logger := base.With().Str("request_id", id).Logger()
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		event := logger.Info()
+
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		}
+
+		event = event.
+			Str("method", c.Request.Method).
+			Str("route", c.FullPath()).
+			Str("path", c.Request.URL.Path).
+			Str("query", RedactQuery(c.Request.URL.Query())).
+			Int("status", status).
+			Float64("latency_ms", float64(time.Since(start).Microseconds())/1000).
+			Int("bytes", c.Writer.Size()).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent())
+
+		if user, ok := auth.UserFrom(c); ok {
+			event = event.Int("user_id", user.ID)
+		}
+
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			event = event.Str("trace_id", sc.TraceID().String())
+		}
+
+		if len(c.Errors) > 0 {
+			event = event.Str("error", c.Errors.String())
+		}
+
+		if logger.GetLevel() <= zerolog.DebugLevel {
+			event = event.Interface("headers", RedactHeaders(c.Request.Header))
+		}
+
+		event.Msg("request")
+	}
+}
+
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				FromContext(c.Request.Context()).Error().
+					Str("panic", fmt.Sprint(r)).
+					Str("stack", string(debug.Stack())).
+					Msg("recovered from panic")
+
+				apierr.Write(c, apierr.Internal(fmt.Errorf("panic: %v", r)))
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+func RedactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+
+	for name, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			out[name] = redacted
+			continue
+		}
+
+		out[name] = strings.Join(values, ", ")
+	}
+
+	return out
+}
+
+func RedactQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+
+	clean := url.Values{}
+
+	for name, values := range q {
+		if isSensitive(name) {
+			clean.Set(name, redacted)
+			continue
+		}
+
+		clean[name] = values
+	}
+
+	return clean.Encode()
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("Cookie", "oidc_state=secret")
+	h.Set("User-Agent", "Test")
+
+	got := RedactHeaders(h)
+
+	if got["Authorization"] != redacted || got["Cookie"] != redacted || got["User-Agent"] != "Test" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	q := url.Values{"code": {"abc"}, "state": {"xyz"}, "new_password": {"hunter2"}, "page": {"2"}}
+
+	want := "code=%5BREDACTED%5D&new_password=%5BREDACTED%5D&page=2&state=%5BREDACTED%5D"
+
+	if got := RedactQuery(q); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -1,41 +1,223 @@
 package middlewares
 
 import (
-	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/apikey"
+	"github.com/richardpanda/composition/server/api/auth"
+	"github.com/richardpanda/composition/server/api/health"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/logging"
+	"github.com/richardpanda/composition/server/api/metrics"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/throttle"
 	"github.com/richardpanda/composition/server/api/types"
 )
 
-func DB(db *sql.DB) gin.HandlerFunc {
+func DeletionPolicy(policy models.DeletionPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Set("db", db)
+		c.Set("deletionPolicy", policy)
+		c.Next()
+	}
+}
+
+// Deprecation announces that the routes it guards are deprecated (RFC 9745)
+// and, when sunset is set, when they will be removed (RFC 8594).
+func Deprecation(deprecatedAt, sunset time.Time, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !deprecatedAt.IsZero() {
+			c.Header("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+		}
+
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+
+		if successor != "" {
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+
+		c.Next()
+	}
+}
+
+func Health(h *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("health", h)
+		c.Next()
+	}
+}
+
+func Keys(ks *keys.KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("keys", ks)
+		c.Next()
+	}
+}
+
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("metrics", m)
+		c.Next()
+	}
+}
+
+func OIDC(providers oidc.Providers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("oidc", providers)
+		c.Next()
+	}
+}
+
+func PasswordPolicy(policy *password.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("passwordPolicy", policy)
+		c.Next()
+	}
+}
+
+func Store(s models.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("store", s.WithContext(c.Request.Context()))
+		c.Next()
+	}
+}
+
+func Throttle(t *throttle.Signin) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("throttle", t)
+		c.Next()
+	}
+}
+
+func TokenLifetimes(l types.TokenLifetimes) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("tokenLifetimes", l)
 		c.Next()
 	}
 }
 
 func Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.Request.Header.Get("Authorization")
+		if c.Request.Header.Get("Authorization") == "" {
+			c.Error(apierr.Unauthorized("Authorization header is required."))
+			c.Abort()
+			return
+		}
 
-		if authHeader == "" {
-			c.AbortWithStatusJSON(401, gin.H{"message": "Authorization header is required."})
+		if err := authenticate(c); err != nil {
+			c.Error(err)
+			c.Abort()
 			return
 		}
 
-		tokenString := authHeader[len("Bearer "):]
+		c.Next()
+	}
+}
 
-		t, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return types.JWTSecret, nil
-		})
+func OptionalAuthenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Header.Get("Authorization") == "" {
+			c.Next()
+			return
+		}
 
-		if err != nil || !t.Valid {
-			c.AbortWithStatusJSON(400, gin.H{"message": "Invalid token."})
+		if err := authenticate(c); err != nil {
+			c.Error(err)
+			c.Abort()
 			return
 		}
 
-		c.Set("user", t.Claims.(jwt.MapClaims))
 		c.Next()
 	}
 }
+
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if u, ok := auth.UserFrom(c); ok && u.HasScope(scope) {
+			c.Next()
+			return
+		}
+
+		c.Error(apierr.Forbidden("Insufficient scope."))
+		c.Abort()
+	}
+}
+
+func authenticate(c *gin.Context) error {
+	tokenString, ok := auth.BearerToken(c.Request.Header.Get("Authorization"))
+
+	if !ok {
+		return apierr.Unauthorized("Authorization header must use the Bearer scheme.")
+	}
+
+	if apikey.IsKey(tokenString) {
+		return authenticateAPIKey(c, tokenString)
+	}
+
+	ks := c.MustGet("keys").(*keys.KeySet)
+	claims, err := auth.ParseSessionToken(ks, tokenString)
+
+	if err != nil {
+		return apierr.Unauthorized("Invalid token.")
+	}
+
+	store := c.MustGet("store").(models.Store)
+	session, err := store.GetSession(claims.Id)
+
+	if err != nil && err != models.ErrNotFound {
+		return err
+	}
+
+	if err == models.ErrNotFound || session.Revoked || session.UserID != claims.ID {
+		return apierr.Unauthorized("Session has been revoked.")
+	}
+
+	if err := store.TouchSession(claims.Id); err != nil {
+		logging.FromContext(c.Request.Context()).Warn().Err(err).Msg("touching session")
+	}
+
+	auth.SetUser(c, &auth.CurrentUser{
+		ID:        claims.ID,
+		Username:  claims.Username,
+		SessionID: claims.Id,
+		Scopes:    []string{types.ScopeAccount, types.ScopeArticlesRead, types.ScopeArticlesWrite},
+	})
+
+	return nil
+}
+
+func authenticateAPIKey(c *gin.Context, key string) error {
+	store := c.MustGet("store").(models.Store)
+	k, err := store.GetAPIKeyByHash(apikey.Hash(key))
+
+	if err == models.ErrNotFound {
+		return apierr.Unauthorized("Invalid token.")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now()) {
+		return apierr.Unauthorized("API key has expired.")
+	}
+
+	if err := store.TouchAPIKey(k.ID); err != nil {
+		logging.FromContext(c.Request.Context()).Warn().Err(err).Msg("touching API key")
+	}
+
+	auth.SetUser(c, &auth.CurrentUser{
+		ID:       k.UserID,
+		Username: k.Username,
+		Scopes:   k.Scopes,
+	})
+
+	return nil
+}
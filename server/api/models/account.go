@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type DeletionPolicy string
+
+const (
+	DeletionPolicyDelete    DeletionPolicy = "delete"
+	DeletionPolicyAnonymize DeletionPolicy = "anonymize"
+)
+
+// anonymousPrefix starts the username of every anonymized account. New
+// accounts cannot take it, so the placeholder is always free.
+const anonymousPrefix = "deleted-"
+
+const anonymizeUserQuery = "UPDATE users SET username = $2, email = $3, password = '', password_set = FALSE WHERE id = $1;"
+const deleteUserArticlesQuery = "DELETE FROM articles WHERE user_id = $1;"
+const deleteUserQuery = "DELETE FROM users WHERE id = $1;"
+
+var deleteUserCredentialsQueries = []string{
+	"DELETE FROM sessions WHERE user_id = $1;",
+	"DELETE FROM api_keys WHERE user_id = $1;",
+	"DELETE FROM recovery_codes WHERE user_id = $1;",
+	"DELETE FROM challenges WHERE user_id = $1;",
+	"DELETE FROM two_factor WHERE user_id = $1;",
+	"DELETE FROM identities WHERE user_id = $1;",
+}
+
+func ParseDeletionPolicy(s string) (DeletionPolicy, error) {
+	switch p := DeletionPolicy(s); p {
+	case "":
+		return DeletionPolicyDelete, nil
+	case DeletionPolicyDelete, DeletionPolicyAnonymize:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown deletion policy %q", s)
+}
+
+func anonymousUsername(userID int) string {
+	return fmt.Sprintf("%s%d", anonymousPrefix, userID)
+}
+
+func reservedUsername(username string) bool {
+	return strings.HasPrefix(username, anonymousPrefix)
+}
+
+func AnonymizeUser(ctx context.Context, q Querier, userID int) error {
+	if err := deleteUserCredentials(ctx, q, userID); err != nil {
+		return err
+	}
+
+	username := anonymousUsername(userID)
+	_, err := exec(ctx, q, "AnonymizeUser", anonymizeUserQuery, userID, username, username+"@deleted.invalid")
+	return err
+}
+
+func DeleteUser(ctx context.Context, q Querier, userID int) error {
+	if err := deleteUserCredentials(ctx, q, userID); err != nil {
+		return err
+	}
+
+	if _, err := exec(ctx, q, "DeleteUser", deleteUserArticlesQuery, userID); err != nil {
+		return err
+	}
+
+	_, err := exec(ctx, q, "DeleteUser", deleteUserQuery, userID)
+	return err
+}
+
+func deleteUserCredentials(ctx context.Context, q Querier, userID int) error {
+	for _, statement := range deleteUserCredentialsQueries {
+		if _, err := exec(ctx, q, "DeleteUserCredentials", statement, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
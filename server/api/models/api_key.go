@@ -0,0 +1,71 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type APIKey struct {
+	ID         int
+	UserID     int
+	Username   string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+}
+
+const createAPIKeyQuery = `
+	INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+	RETURNING id, created_at;
+`
+const deleteAPIKeyQuery = "DELETE FROM api_keys WHERE id = $1 AND user_id = $2;"
+const getAPIKeyByHashQuery = `
+	SELECT api_keys.id, user_id, username, scopes, expires_at
+	FROM users, api_keys
+	WHERE users.id = api_keys.user_id AND key_hash = $1;
+`
+const getAPIKeysQuery = `
+	SELECT id, name, prefix, scopes, created_at, last_used_at, expires_at
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY created_at DESC;
+`
+const touchAPIKeyQuery = "UPDATE api_keys SET last_used_at = NOW() WHERE id = $1;"
+
+func CreateAPIKey(ctx context.Context, q Querier, k *APIKey) *sql.Row {
+	return queryRow(ctx, q, "CreateAPIKey", createAPIKeyQuery, k.UserID, k.Name, k.Prefix, k.KeyHash, JoinScopes(k.Scopes), k.ExpiresAt)
+}
+
+func DeleteAPIKey(ctx context.Context, q Querier, id, userID int) (sql.Result, error) {
+	return exec(ctx, q, "DeleteAPIKey", deleteAPIKeyQuery, id, userID)
+}
+
+func GetAPIKeyByHash(ctx context.Context, q Querier, keyHash string) *sql.Row {
+	return queryRow(ctx, q, "GetAPIKeyByHash", getAPIKeyByHashQuery, keyHash)
+}
+
+func GetAPIKeys(ctx context.Context, q Querier, userID int) (*sql.Rows, error) {
+	return query(ctx, q, "GetAPIKeys", getAPIKeysQuery, userID)
+}
+
+func TouchAPIKey(ctx context.Context, q Querier, id int) (sql.Result, error) {
+	return exec(ctx, q, "TouchAPIKey", touchAPIKeyQuery, id)
+}
+
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, ",")
+}
+
+func SplitScopes(scopes string) []string {
+	if scopes == "" {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
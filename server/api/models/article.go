@@ -1,31 +1,37 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"time"
 )
 
+// MaxPage is the last page of article previews served. It keeps the offset
+// far from overflowing.
+const MaxPage = 1000000
+
 type Article struct {
-	UserID int    `json:"id"`
-	Title  string `json:"title"`
-	Body   string `json:"body"`
+	ID        int
+	UserID    int
+	Username  string
+	Title     string
+	Body      string
+	CreatedAt time.Time
 }
 
 const createArticleQuery = "INSERT INTO articles (user_id, title, body, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id;"
-const createArticlesTableQuery = `
-	CREATE TABLE IF NOT EXISTS articles (
-		id         SERIAL       PRIMARY KEY,
-		user_id    SERIAL       REFERENCES users,
-		title      VARCHAR(100)  NOT NULL,
-		body       TEXT         NOT NULL,
-		created_at TIMESTAMP    NOT NULL
-	);
-`
-const dropArticlesTableQuery = "DROP TABLE articles;"
 const getArticleQuery = `
-	SELECT articles.id, title, body, username, created_at
+	SELECT articles.id, user_id, title, body, username, created_at
 	FROM users, articles
 	WHERE users.id = articles.user_id AND articles.id = $1;
 `
+const getArticlesByUserQuery = `
+	SELECT id, title, body, created_at
+	FROM articles
+	WHERE user_id = $1
+	ORDER BY created_at;
+`
 const getLatestArticlePreviewsQuery = `
 	SELECT username, title, articles.id, created_at
 	FROM users, articles
@@ -35,22 +41,25 @@ const getLatestArticlePreviewsQuery = `
 	OFFSET $1;
 `
 
-func CreateArticle(db *sql.DB, a *Article) *sql.Row {
-	return db.QueryRow(createArticleQuery, a.UserID, a.Title, a.Body)
+func checkPage(page int) error {
+	if page < 1 || page > MaxPage {
+		return fmt.Errorf("page must be between 1 and %d, got %d", MaxPage, page)
+	}
+	return nil
 }
 
-func CreateArticlesTable(db *sql.DB) (sql.Result, error) {
-	return db.Exec(createArticlesTableQuery)
+func CreateArticle(ctx context.Context, q Querier, a *Article) *sql.Row {
+	return queryRow(ctx, q, "CreateArticle", createArticleQuery, a.UserID, a.Title, a.Body)
 }
 
-func DropArticlesTable(db *sql.DB) (sql.Result, error) {
-	return db.Exec(dropArticlesTableQuery)
+func GetArticle(ctx context.Context, q Querier, id int) *sql.Row {
+	return queryRow(ctx, q, "GetArticle", getArticleQuery, id)
 }
 
-func GetArticle(db *sql.DB, id int) *sql.Row {
-	return db.QueryRow(getArticleQuery, id)
+func GetArticlesByUser(ctx context.Context, q Querier, userID int) (*sql.Rows, error) {
+	return query(ctx, q, "GetArticlesByUser", getArticlesByUserQuery, userID)
 }
 
-func GetLatestArticlePreviews(db *sql.DB, page int) (*sql.Rows, error) {
-	return db.Query(getLatestArticlePreviewsQuery, (page-1)*10)
+func GetLatestArticlePreviews(ctx context.Context, q Querier, page int) (*sql.Rows, error) {
+	return query(ctx, q, "GetLatestArticlePreviews", getLatestArticlePreviewsQuery, (page-1)*10)
 }
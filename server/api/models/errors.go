@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTimeout     error = &transientError{message: "database operation timed out", timeout: true}
+	ErrUnavailable error = &transientError{message: "database is unavailable"}
+)
+
+type transientError struct {
+	message string
+	timeout bool
+}
+
+func (e *transientError) Error() string {
+	return e.message
+}
+
+func (e *transientError) Timeout() bool {
+	return e.timeout
+}
+
+func (e *transientError) Temporary() bool {
+	return true
+}
+
+var uniqueViolations = map[string]error{
+	"users_username_key":              ErrUsernameTaken,
+	"users_email_key":                 ErrEmailTaken,
+	"identities_provider_subject_key": ErrIdentityTaken,
+}
+
+func translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Name() == "unique_violation":
+			if e, ok := uniqueViolations[pqErr.Constraint]; ok {
+				return e
+			}
+			return ErrConflict
+		case pqErr.Code.Name() == "foreign_key_violation":
+			return ErrUnknownUser
+		case pqErr.Code.Name() == "string_data_right_truncation":
+			return ErrTooLong
+		case pqErr.Code.Name() == "query_canceled":
+			return fmt.Errorf("%w: %v", ErrTimeout, err)
+		case pqErr.Code.Class() == "08", pqErr.Code.Class() == "53", pqErr.Code.Class() == "57":
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+
+		return err
+	}
+
+	var netErr net.Error
+
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestTranslate(t *testing.T) {
+	other := errors.New("other")
+
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&pq.Error{Code: "23505", Constraint: "users_username_key"}, ErrUsernameTaken},
+		{&pq.Error{Code: "23505", Constraint: "users_email_key"}, ErrEmailTaken},
+		{fmt.Errorf("creating identity: %w", &pq.Error{Code: "23505", Constraint: "identities_provider_subject_key"}), ErrIdentityTaken},
+		{&pq.Error{Code: "23505", Constraint: "api_keys_key_hash_key"}, ErrConflict},
+		{&pq.Error{Code: "23503", Constraint: "articles_user_id_fkey"}, ErrUnknownUser},
+		{&pq.Error{Code: "22001"}, ErrTooLong},
+		{&pq.Error{Code: "57014"}, ErrTimeout},
+		{&pq.Error{Code: "53300"}, ErrUnavailable},
+		{&pq.Error{Code: "08006"}, ErrUnavailable},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), ErrTimeout},
+		{driver.ErrBadConn, ErrUnavailable},
+		{other, other},
+		{nil, nil},
+	}
+
+	for _, tc := range cases {
+		if got := translate(tc.err); !errors.Is(got, tc.want) {
+			t.Errorf("translate(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+type slowDriver struct{}
+
+type slowConn struct {
+	fakeConn
+}
+
+func (slowDriver) Open(string) (driver.Conn, error) { return slowConn{}, nil }
+
+func (slowConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("slow", slowDriver{})
+}
+
+func TestPostgresStoreTimesOut(t *testing.T) {
+	db, err := sql.Open("slow", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	start := time.Now()
+
+	if _, err := NewPostgresStore(db, 10*time.Millisecond).GetArticle(1); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("took %s", d)
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Identity struct {
+	UserID    int
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+const createIdentityQuery = `
+	INSERT INTO identities (user_id, provider, subject, email, created_at)
+	VALUES ($1, $2, $3, $4, NOW());
+`
+const getIdentitiesQuery = `
+	SELECT provider, email, created_at
+	FROM identities
+	WHERE user_id = $1
+	ORDER BY created_at;
+`
+const getUserByIdentityQuery = `
+	SELECT users.id, username
+	FROM users, identities
+	WHERE users.id = identities.user_id AND provider = $1 AND subject = $2;
+`
+
+func CreateIdentity(ctx context.Context, q Querier, i *Identity) (sql.Result, error) {
+	return exec(ctx, q, "CreateIdentity", createIdentityQuery, i.UserID, i.Provider, i.Subject, i.Email)
+}
+
+func GetIdentities(ctx context.Context, q Querier, userID int) (*sql.Rows, error) {
+	return query(ctx, q, "GetIdentities", getIdentitiesQuery, userID)
+}
+
+func GetUserByIdentity(ctx context.Context, q Querier, provider, subject string) *sql.Row {
+	return queryRow(ctx, q, "GetUserByIdentity", getUserByIdentityQuery, provider, subject)
+}
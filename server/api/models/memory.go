@@ -0,0 +1,721 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type recoveryCode struct {
+	hash string
+	used bool
+}
+
+type challenge struct {
+	userID    int
+	expiresAt time.Time
+	used      bool
+}
+
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	nextArticleID int
+	nextAPIKeyID  int
+	nextUserID    int
+
+	apiKeys       map[int]*APIKey
+	articles      map[int]*Article
+	challenges    map[string]*challenge
+	identities    []*Identity
+	recoveryCodes map[int][]*recoveryCode
+	sessions      map[string]*Session
+	twoFactor     map[int]*TwoFactor
+	users         map[int]*User
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		apiKeys:       map[int]*APIKey{},
+		articles:      map[int]*Article{},
+		challenges:    map[string]*challenge{},
+		recoveryCodes: map[int][]*recoveryCode{},
+		sessions:      map[string]*Session{},
+		twoFactor:     map[int]*TwoFactor{},
+		users:         map[int]*User{},
+	}
+}
+
+func (s *MemoryStore) WithContext(ctx context.Context) Store {
+	return s
+}
+
+func (s *MemoryStore) CreateArticle(a *Article) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[a.UserID]; !ok {
+		return 0, ErrUnknownUser
+	}
+
+	s.nextArticleID++
+	s.articles[s.nextArticleID] = &Article{
+		ID:        s.nextArticleID,
+		UserID:    a.UserID,
+		Title:     a.Title,
+		Body:      a.Body,
+		CreatedAt: time.Now(),
+	}
+
+	return s.nextArticleID, nil
+}
+
+func (s *MemoryStore) GetArticle(id int) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.articles[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return s.withUsername(a), nil
+}
+
+func (s *MemoryStore) GetArticlePreviews(page int) ([]Article, error) {
+	if err := checkPage(page); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	articles := []Article{}
+
+	for _, a := range s.articles {
+		preview := s.withUsername(a)
+		preview.Body = ""
+		articles = append(articles, *preview)
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].CreatedAt.Equal(articles[j].CreatedAt) {
+			return articles[i].CreatedAt.After(articles[j].CreatedAt)
+		}
+		return articles[i].ID > articles[j].ID
+	})
+
+	start := (page - 1) * 10
+
+	if start >= len(articles) {
+		return []Article{}, nil
+	}
+
+	end := start + 10
+
+	if end > len(articles) {
+		end = len(articles)
+	}
+
+	return articles[start:end], nil
+}
+
+func (s *MemoryStore) GetArticlesByUser(userID int) ([]Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	articles := []Article{}
+
+	for _, a := range s.articles {
+		if a.UserID == userID {
+			articles = append(articles, *a)
+		}
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].CreatedAt.Equal(articles[j].CreatedAt) {
+			return articles[i].CreatedAt.Before(articles[j].CreatedAt)
+		}
+		return articles[i].ID < articles[j].ID
+	})
+
+	return articles, nil
+}
+
+func (s *MemoryStore) CreateUser(u *User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reservedUsername(u.Username) {
+		return 0, ErrUsernameTaken
+	}
+
+	for _, existing := range s.users {
+		if existing.Username == u.Username {
+			return 0, ErrUsernameTaken
+		}
+
+		if existing.Email == u.Email {
+			return 0, ErrEmailTaken
+		}
+	}
+
+	s.nextUserID++
+	s.users[s.nextUserID] = &User{
+		ID:          s.nextUserID,
+		Username:    u.Username,
+		Email:       u.Email,
+		Password:    u.Password,
+		PasswordSet: u.Password != "",
+	}
+
+	return s.nextUserID, nil
+}
+
+func (s *MemoryStore) GetUserByID(id int) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	clone := *u
+	return &clone, nil
+}
+
+func (s *MemoryStore) GetUserByUsername(username string) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.Username == username })
+}
+
+func (s *MemoryStore) GetUserByEmail(email string) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.Email == email })
+}
+
+func (s *MemoryStore) UpdateUserPassword(id int, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		u.Password = hash
+		u.PasswordSet = true
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) AnonymizeUser(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := anonymousUsername(id)
+
+	// Match the unique constraints on users.
+	for otherID, other := range s.users {
+		if otherID != id && other.Username == username {
+			return ErrUsernameTaken
+		}
+
+		if otherID != id && other.Email == username+"@deleted.invalid" {
+			return ErrEmailTaken
+		}
+	}
+
+	s.deleteCredentials(id)
+
+	if u, ok := s.users[id]; ok {
+		u.Username = username
+		u.Email = u.Username + "@deleted.invalid"
+		u.Password = ""
+		u.PasswordSet = false
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteCredentials(id)
+
+	for articleID, a := range s.articles {
+		if a.UserID == id {
+			delete(s.articles, articleID)
+		}
+	}
+
+	delete(s.users, id)
+
+	return nil
+}
+
+func (s *MemoryStore) CreateSession(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[session.UserID]; !ok {
+		return ErrUnknownUser
+	}
+
+	if _, ok := s.sessions[session.ID]; ok {
+		return ErrConflict
+	}
+
+	now := time.Now()
+	s.sessions[session.ID] = &Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  truncate(session.UserAgent, 255),
+		IP:         truncate(session.IP, 45),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  session.ExpiresAt,
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) GetSession(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[id]
+
+	if !ok || expired(session.ExpiresAt, time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	clone := *session
+	return &clone, nil
+}
+
+func (s *MemoryStore) GetSessions(userID int) ([]Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []Session{}
+
+	for _, session := range s.sessions {
+		if session.UserID == userID && !session.Revoked && !expired(session.ExpiresAt, time.Now()) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (s *MemoryStore) PruneSessions(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+
+	for id, session := range s.sessions {
+		if session.Revoked && session.LastSeenAt.Before(before) || expired(session.ExpiresAt, before) {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (s *MemoryStore) ExtendSession(id string, userID int, expiresAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+
+	if !ok || session.UserID != userID || session.Revoked || expired(session.ExpiresAt, time.Now()) {
+		return ErrNotFound
+	}
+
+	session.ExpiresAt = expiresAt
+
+	return nil
+}
+
+func (s *MemoryStore) RevokeSession(id string, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+
+	if !ok || session.UserID != userID || session.Revoked {
+		return ErrNotFound
+	}
+
+	session.Revoked = true
+
+	return nil
+}
+
+func (s *MemoryStore) RevokeOtherSessions(userID int, keepID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID && id != keepID {
+			session.Revoked = true
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) TouchSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if session, ok := s.sessions[id]; ok && session.LastSeenAt.Before(now.Add(-time.Minute)) {
+		session.LastSeenAt = now
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) CreateAPIKey(k *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[k.UserID]; !ok {
+		return ErrUnknownUser
+	}
+
+	for _, existing := range s.apiKeys {
+		if existing.KeyHash == k.KeyHash {
+			return ErrConflict
+		}
+	}
+
+	s.nextAPIKeyID++
+	k.ID = s.nextAPIKeyID
+	k.CreatedAt = time.Now()
+
+	clone := *k
+	clone.Scopes = append([]string{}, k.Scopes...)
+	s.apiKeys[k.ID] = &clone
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteAPIKey(id, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.apiKeys[id]; !ok || k.UserID != userID {
+		return ErrNotFound
+	}
+
+	delete(s.apiKeys, id)
+
+	return nil
+}
+
+func (s *MemoryStore) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.KeyHash == keyHash {
+			clone := *k
+			clone.Username = s.users[k.UserID].Username
+			return &clone, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetAPIKeys(userID int) ([]APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apiKeys := []APIKey{}
+
+	for _, k := range s.apiKeys {
+		if k.UserID == userID {
+			apiKeys = append(apiKeys, *k)
+		}
+	}
+
+	sort.Slice(apiKeys, func(i, j int) bool {
+		if !apiKeys[i].CreatedAt.Equal(apiKeys[j].CreatedAt) {
+			return apiKeys[i].CreatedAt.After(apiKeys[j].CreatedAt)
+		}
+		return apiKeys[i].ID > apiKeys[j].ID
+	})
+
+	return apiKeys, nil
+}
+
+func (s *MemoryStore) TouchAPIKey(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.apiKeys[id]; ok {
+		now := time.Now()
+		k.LastUsedAt = &now
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) GetTwoFactor(userID int) (*TwoFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.twoFactor[userID]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	clone := *t
+	return &clone, nil
+}
+
+func (s *MemoryStore) SetTwoFactorSecret(userID int, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return ErrUnknownUser
+	}
+
+	s.twoFactor[userID] = &TwoFactor{Secret: secret}
+
+	return nil
+}
+
+func (s *MemoryStore) EnableTwoFactor(userID int, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make([]*recoveryCode, len(recoveryCodeHashes))
+
+	for i, codeHash := range recoveryCodeHashes {
+		codes[i] = &recoveryCode{hash: codeHash}
+	}
+
+	s.recoveryCodes[userID] = codes
+
+	if t, ok := s.twoFactor[userID]; ok {
+		t.Enabled = true
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) UseRecoveryCode(userID int, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range s.recoveryCodes[userID] {
+		if code.hash == codeHash && !code.used {
+			code.used = true
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (s *MemoryStore) UseTOTPStep(userID int, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.twoFactor[userID]
+
+	if !ok || step <= t.LastStep {
+		return ErrNotFound
+	}
+
+	t.LastStep = step
+
+	return nil
+}
+
+func (s *MemoryStore) CreateChallenge(id string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return ErrUnknownUser
+	}
+
+	if _, ok := s.challenges[id]; ok {
+		return ErrConflict
+	}
+
+	s.challenges[id] = &challenge{userID: userID, expiresAt: expiresAt}
+
+	return nil
+}
+
+func (s *MemoryStore) PruneChallenges(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+
+	for id, c := range s.challenges {
+		if c.expiresAt.Before(before) {
+			delete(s.challenges, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (s *MemoryStore) CompleteChallenge(id string, userID int, step int64, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.challenges[id]
+
+	if !ok || c.userID != userID || c.used || !time.Now().Before(c.expiresAt) {
+		return ErrChallengeUsed
+	}
+
+	if step > 0 {
+		t, ok := s.twoFactor[userID]
+
+		if !ok || step <= t.LastStep {
+			return ErrNotFound
+		}
+
+		c.used = true
+		t.LastStep = step
+
+		return nil
+	}
+
+	for _, code := range s.recoveryCodes[userID] {
+		if code.hash == codeHash && !code.used {
+			c.used = true
+			code.used = true
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (s *MemoryStore) CreateIdentity(i *Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[i.UserID]; !ok {
+		return ErrUnknownUser
+	}
+
+	for _, existing := range s.identities {
+		if existing.Provider == i.Provider && existing.Subject == i.Subject {
+			return ErrIdentityTaken
+		}
+	}
+
+	clone := *i
+	clone.CreatedAt = time.Now()
+	s.identities = append(s.identities, &clone)
+
+	return nil
+}
+
+func (s *MemoryStore) GetIdentities(userID int) ([]Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identities := []Identity{}
+
+	for _, i := range s.identities {
+		if i.UserID == userID {
+			identities = append(identities, *i)
+		}
+	}
+
+	return identities, nil
+}
+
+func (s *MemoryStore) GetUserByIdentity(provider, subject string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, i := range s.identities {
+		if i.Provider == provider && i.Subject == subject {
+			clone := *s.users[i.UserID]
+			return &clone, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) findUser(match func(*User) bool) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if match(u) {
+			clone := *u
+			return &clone, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) withUsername(a *Article) *Article {
+	clone := *a
+	clone.Username = s.users[a.UserID].Username
+	return &clone
+}
+
+func (s *MemoryStore) deleteCredentials(userID int) {
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+
+	for id, k := range s.apiKeys {
+		if k.UserID == userID {
+			delete(s.apiKeys, id)
+		}
+	}
+
+	for id, c := range s.challenges {
+		if c.userID == userID {
+			delete(s.challenges, id)
+		}
+	}
+
+	delete(s.recoveryCodes, userID)
+	delete(s.twoFactor, userID)
+
+	identities := s.identities[:0]
+
+	for _, i := range s.identities {
+		if i.UserID != userID {
+			identities = append(identities, i)
+		}
+	}
+
+	s.identities = identities
+}
@@ -0,0 +1,500 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type PostgresStore struct {
+	ctx     context.Context
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewPostgresStore(db *sql.DB, timeout time.Duration) *PostgresStore {
+	return &PostgresStore{ctx: context.Background(), db: db, timeout: timeout}
+}
+
+func (s *PostgresStore) WithContext(ctx context.Context) Store {
+	return &PostgresStore{ctx: ctx, db: s.db, timeout: s.timeout}
+}
+
+func (s *PostgresStore) operation() (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(s.ctx)
+	}
+	return context.WithTimeout(s.ctx, s.timeout)
+}
+
+func (s *PostgresStore) CreateArticle(a *Article) (int, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	var id int
+	err := CreateArticle(ctx, s.db, a).Scan(&id)
+	return id, translate(err)
+}
+
+func (s *PostgresStore) GetArticle(id int) (*Article, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	a := &Article{}
+
+	if err := GetArticle(ctx, s.db, id).Scan(&a.ID, &a.UserID, &a.Title, &a.Body, &a.Username, &a.CreatedAt); err != nil {
+		return nil, notFound(err)
+	}
+
+	return a, nil
+}
+
+func (s *PostgresStore) GetArticlePreviews(page int) ([]Article, error) {
+	if err := checkPage(page); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	rows, err := GetLatestArticlePreviews(ctx, s.db, page)
+
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	defer rows.Close()
+
+	articles := []Article{}
+
+	for rows.Next() {
+		var a Article
+
+		if err := rows.Scan(&a.Username, &a.Title, &a.ID, &a.CreatedAt); err != nil {
+			return nil, translate(err)
+		}
+
+		articles = append(articles, a)
+	}
+
+	return articles, translate(rows.Err())
+}
+
+func (s *PostgresStore) GetArticlesByUser(userID int) ([]Article, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	rows, err := GetArticlesByUser(ctx, s.db, userID)
+
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	defer rows.Close()
+
+	articles := []Article{}
+
+	for rows.Next() {
+		a := Article{UserID: userID}
+
+		if err := rows.Scan(&a.ID, &a.Title, &a.Body, &a.CreatedAt); err != nil {
+			return nil, translate(err)
+		}
+
+		articles = append(articles, a)
+	}
+
+	return articles, translate(rows.Err())
+}
+
+func (s *PostgresStore) CreateUser(u *User) (int, error) {
+	if reservedUsername(u.Username) {
+		return 0, ErrUsernameTaken
+	}
+
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	var id int
+	err := CreateUser(ctx, s.db, u).Scan(&id)
+	return id, translate(err)
+}
+
+func (s *PostgresStore) GetUserByID(id int) (*User, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	u := &User{}
+
+	if err := GetUserByID(ctx, s.db, id).Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.PasswordSet); err != nil {
+		return nil, notFound(err)
+	}
+
+	return u, nil
+}
+
+func (s *PostgresStore) GetUserByUsername(username string) (*User, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	u := &User{}
+
+	if err := GetUserByUsername(ctx, s.db, username).Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.PasswordSet); err != nil {
+		return nil, notFound(err)
+	}
+
+	return u, nil
+}
+
+func (s *PostgresStore) GetUserByEmail(email string) (*User, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	u := &User{Email: email}
+
+	if err := GetUserByEmail(ctx, s.db, email).Scan(&u.ID, &u.Username); err != nil {
+		return nil, notFound(err)
+	}
+
+	return u, nil
+}
+
+func (s *PostgresStore) UpdateUserPassword(id int, hash string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := UpdateUserPassword(ctx, s.db, id, hash)
+	return translate(err)
+}
+
+func (s *PostgresStore) AnonymizeUser(id int) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return translate(Transact(ctx, s.db, func(tx *sql.Tx) error {
+		return AnonymizeUser(ctx, tx, id)
+	}))
+}
+
+func (s *PostgresStore) DeleteUser(id int) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return translate(Transact(ctx, s.db, func(tx *sql.Tx) error {
+		return DeleteUser(ctx, tx, id)
+	}))
+}
+
+func (s *PostgresStore) CreateSession(session *Session) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := CreateSession(ctx, s.db, session)
+	return translate(err)
+}
+
+func (s *PostgresStore) GetSession(id string) (*Session, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	session := &Session{ID: id}
+
+	if err := GetSession(ctx, s.db, id).Scan(&session.UserID, &session.CreatedAt, &session.ExpiresAt, &session.Revoked); err != nil {
+		return nil, notFound(err)
+	}
+
+	return session, nil
+}
+
+func (s *PostgresStore) GetSessions(userID int) ([]Session, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	rows, err := GetSessions(ctx, s.db, userID)
+
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	defer rows.Close()
+
+	sessions := []Session{}
+
+	for rows.Next() {
+		session := Session{UserID: userID}
+
+		if err := rows.Scan(&session.ID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, translate(err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, translate(rows.Err())
+}
+
+func (s *PostgresStore) PruneSessions(before time.Time) (int64, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	result, err := PruneSessions(ctx, s.db, before)
+
+	if err != nil {
+		return 0, translate(err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) ExtendSession(id string, userID int, expiresAt *time.Time) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return affected(ExtendSession(ctx, s.db, id, userID, expiresAt))
+}
+
+func (s *PostgresStore) RevokeSession(id string, userID int) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return affected(RevokeSession(ctx, s.db, id, userID))
+}
+
+func (s *PostgresStore) RevokeOtherSessions(userID int, keepID string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := RevokeOtherSessions(ctx, s.db, userID, keepID)
+	return translate(err)
+}
+
+func (s *PostgresStore) TouchSession(id string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := TouchSession(ctx, s.db, id)
+	return translate(err)
+}
+
+func (s *PostgresStore) CreateAPIKey(k *APIKey) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return translate(CreateAPIKey(ctx, s.db, k).Scan(&k.ID, &k.CreatedAt))
+}
+
+func (s *PostgresStore) DeleteAPIKey(id, userID int) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return affected(DeleteAPIKey(ctx, s.db, id, userID))
+}
+
+func (s *PostgresStore) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	k := &APIKey{KeyHash: keyHash}
+
+	var scopes string
+
+	if err := GetAPIKeyByHash(ctx, s.db, keyHash).Scan(&k.ID, &k.UserID, &k.Username, &scopes, &k.ExpiresAt); err != nil {
+		return nil, notFound(err)
+	}
+
+	k.Scopes = SplitScopes(scopes)
+
+	return k, nil
+}
+
+func (s *PostgresStore) GetAPIKeys(userID int) ([]APIKey, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	rows, err := GetAPIKeys(ctx, s.db, userID)
+
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	defer rows.Close()
+
+	apiKeys := []APIKey{}
+
+	for rows.Next() {
+		var (
+			k      = APIKey{UserID: userID}
+			scopes string
+		)
+
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt); err != nil {
+			return nil, translate(err)
+		}
+
+		k.Scopes = SplitScopes(scopes)
+		apiKeys = append(apiKeys, k)
+	}
+
+	return apiKeys, translate(rows.Err())
+}
+
+func (s *PostgresStore) TouchAPIKey(id int) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := TouchAPIKey(ctx, s.db, id)
+	return translate(err)
+}
+
+func (s *PostgresStore) GetTwoFactor(userID int) (*TwoFactor, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	t := &TwoFactor{}
+
+	if err := GetTwoFactor(ctx, s.db, userID).Scan(&t.Secret, &t.Enabled, &t.LastStep); err != nil {
+		return nil, notFound(err)
+	}
+
+	return t, nil
+}
+
+func (s *PostgresStore) SetTwoFactorSecret(userID int, secret string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := SetTwoFactorSecret(ctx, s.db, userID, secret)
+	return translate(err)
+}
+
+func (s *PostgresStore) EnableTwoFactor(userID int, recoveryCodeHashes []string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return translate(Transact(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := DeleteRecoveryCodes(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		for _, codeHash := range recoveryCodeHashes {
+			if _, err := CreateRecoveryCode(ctx, tx, userID, codeHash); err != nil {
+				return err
+			}
+		}
+
+		_, err := EnableTwoFactor(ctx, tx, userID)
+		return err
+	}))
+}
+
+func (s *PostgresStore) UseRecoveryCode(userID int, codeHash string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	var id int
+	return notFound(UseRecoveryCode(ctx, s.db, userID, codeHash).Scan(&id))
+}
+
+func (s *PostgresStore) UseTOTPStep(userID int, step int64) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return affected(UseTOTPStep(ctx, s.db, userID, step))
+}
+
+func (s *PostgresStore) CreateChallenge(id string, userID int, expiresAt time.Time) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := CreateChallenge(ctx, s.db, id, userID, expiresAt)
+	return translate(err)
+}
+
+func (s *PostgresStore) PruneChallenges(before time.Time) (int64, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	result, err := PruneChallenges(ctx, s.db, before)
+
+	if err != nil {
+		return 0, translate(err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *PostgresStore) CompleteChallenge(id string, userID int, step int64, codeHash string) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	return translate(Transact(ctx, s.db, func(tx *sql.Tx) error {
+		return CompleteChallenge(ctx, tx, id, userID, step, codeHash)
+	}))
+}
+
+func (s *PostgresStore) CreateIdentity(i *Identity) error {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	_, err := CreateIdentity(ctx, s.db, i)
+	return translate(err)
+}
+
+func (s *PostgresStore) GetIdentities(userID int) ([]Identity, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	rows, err := GetIdentities(ctx, s.db, userID)
+
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	defer rows.Close()
+
+	identities := []Identity{}
+
+	for rows.Next() {
+		i := Identity{UserID: userID}
+
+		if err := rows.Scan(&i.Provider, &i.Email, &i.CreatedAt); err != nil {
+			return nil, translate(err)
+		}
+
+		identities = append(identities, i)
+	}
+
+	return identities, translate(rows.Err())
+}
+
+func (s *PostgresStore) GetUserByIdentity(provider, subject string) (*User, error) {
+	ctx, cancel := s.operation()
+	defer cancel()
+
+	u := &User{}
+
+	if err := GetUserByIdentity(ctx, s.db, provider, subject).Scan(&u.ID, &u.Username); err != nil {
+		return nil, notFound(err)
+	}
+
+	return u, nil
+}
+
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return translate(err)
+}
+
+func affected(result sql.Result, err error) error {
+	if err != nil {
+		return translate(err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Session struct {
+	ID         string
+	UserID     int
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// ExpiresAt is nil for sessions whose tokens do not expire.
+	ExpiresAt *time.Time
+	Revoked   bool
+}
+
+const createSessionQuery = `
+	INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+	VALUES ($1, $2, $3, $4, NOW(), NOW(), $5);
+`
+const getSessionQuery = `
+	SELECT user_id, created_at, expires_at, revoked_at IS NOT NULL
+	FROM sessions
+	WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+`
+const getSessionsQuery = `
+	SELECT id, user_agent, ip, created_at, last_seen_at, expires_at
+	FROM sessions
+	WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	ORDER BY last_seen_at DESC;
+`
+const pruneSessionsQuery = `
+	DELETE FROM sessions
+	WHERE (revoked_at IS NOT NULL AND last_seen_at < $1) OR expires_at < $1;
+`
+const extendSessionQuery = `
+	UPDATE sessions SET expires_at = $3
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW());
+`
+const revokeOtherSessionsQuery = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+`
+const revokeSessionQuery = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+`
+const touchSessionQuery = `
+	UPDATE sessions SET last_seen_at = NOW()
+	WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '1 minute';
+`
+
+func CreateSession(ctx context.Context, q Querier, s *Session) (sql.Result, error) {
+	return exec(ctx, q, "CreateSession", createSessionQuery, s.ID, s.UserID, truncate(s.UserAgent, 255), truncate(s.IP, 45), s.ExpiresAt)
+}
+
+func ExtendSession(ctx context.Context, q Querier, id string, userID int, expiresAt *time.Time) (sql.Result, error) {
+	return exec(ctx, q, "ExtendSession", extendSessionQuery, id, userID, expiresAt)
+}
+
+func GetSession(ctx context.Context, q Querier, id string) *sql.Row {
+	return queryRow(ctx, q, "GetSession", getSessionQuery, id)
+}
+
+func GetSessions(ctx context.Context, q Querier, userID int) (*sql.Rows, error) {
+	return query(ctx, q, "GetSessions", getSessionsQuery, userID)
+}
+
+func PruneSessions(ctx context.Context, q Querier, before time.Time) (sql.Result, error) {
+	return exec(ctx, q, "PruneSessions", pruneSessionsQuery, before)
+}
+
+func RevokeOtherSessions(ctx context.Context, q Querier, userID int, keepID string) (sql.Result, error) {
+	return exec(ctx, q, "RevokeOtherSessions", revokeOtherSessionsQuery, userID, keepID)
+}
+
+func RevokeSession(ctx context.Context, q Querier, id string, userID int) (sql.Result, error) {
+	return exec(ctx, q, "RevokeSession", revokeSessionQuery, id, userID)
+}
+
+func TouchSession(ctx context.Context, q Querier, id string) (sql.Result, error) {
+	return exec(ctx, q, "TouchSession", touchSessionQuery, id)
+}
+
+// expired reports whether a session expiring at expiresAt has expired by t.
+func expired(expiresAt *time.Time, t time.Time) bool {
+	return expiresAt != nil && !expiresAt.After(t)
+}
+
+// truncate makes s valid UTF-8, which Postgres requires, and cuts it to n
+// characters to fit the column.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+
+	if utf8.RuneCountInString(s) > n {
+		return string([]rune(s)[:n])
+	}
+
+	return s
+}
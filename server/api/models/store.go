@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrUsernameTaken = errors.New("username is taken")
+	ErrEmailTaken    = errors.New("email is taken")
+	ErrIdentityTaken = errors.New("identity is already linked")
+	ErrConflict      = errors.New("record already exists")
+	ErrUnknownUser   = errors.New("user does not exist")
+	ErrTooLong       = errors.New("value is too long")
+	ErrChallengeUsed = errors.New("challenge is used or expired")
+)
+
+type ArticleStore interface {
+	CreateArticle(a *Article) (int, error)
+	GetArticle(id int) (*Article, error)
+	GetArticlePreviews(page int) ([]Article, error)
+	GetArticlesByUser(userID int) ([]Article, error)
+}
+
+type UserStore interface {
+	CreateUser(u *User) (int, error)
+	GetUserByID(id int) (*User, error)
+	GetUserByUsername(username string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	UpdateUserPassword(id int, hash string) error
+	AnonymizeUser(id int) error
+	DeleteUser(id int) error
+}
+
+type SessionStore interface {
+	CreateSession(s *Session) error
+	// ExtendSession moves the expiry of a live session and fails with
+	// ErrNotFound if it was revoked or has expired.
+	ExtendSession(id string, userID int, expiresAt *time.Time) error
+	GetSession(id string) (*Session, error)
+	GetSessions(userID int) ([]Session, error)
+	PruneSessions(before time.Time) (int64, error)
+	RevokeSession(id string, userID int) error
+	RevokeOtherSessions(userID int, keepID string) error
+	TouchSession(id string) error
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(k *APIKey) error
+	DeleteAPIKey(id, userID int) error
+	GetAPIKeyByHash(keyHash string) (*APIKey, error)
+	GetAPIKeys(userID int) ([]APIKey, error)
+	TouchAPIKey(id int) error
+}
+
+type TwoFactorStore interface {
+	GetTwoFactor(userID int) (*TwoFactor, error)
+	SetTwoFactorSecret(userID int, secret string) error
+	EnableTwoFactor(userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) error
+	// UseTOTPStep records an accepted TOTP step and fails with ErrNotFound
+	// unless it is later than the last one.
+	UseTOTPStep(userID int, step int64) error
+	CreateChallenge(id string, userID int, expiresAt time.Time) error
+	PruneChallenges(before time.Time) (int64, error)
+	// CompleteChallenge uses an unexpired challenge together with a TOTP
+	// step, or with a recovery code when step is zero, and uses neither
+	// unless both are accepted. It fails with ErrChallengeUsed if the
+	// challenge was used or has expired and with ErrNotFound if the code
+	// is not accepted.
+	CompleteChallenge(id string, userID int, step int64, codeHash string) error
+}
+
+type IdentityStore interface {
+	CreateIdentity(i *Identity) error
+	GetIdentities(userID int) ([]Identity, error)
+	GetUserByIdentity(provider, subject string) (*User, error)
+}
+
+type Store interface {
+	ArticleStore
+	UserStore
+	SessionStore
+	APIKeyStore
+	TwoFactorStore
+	IdentityStore
+	WithContext(ctx context.Context) Store
+}
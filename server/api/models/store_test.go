@@ -0,0 +1,426 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	_ "github.com/lib/pq"
+	"github.com/richardpanda/composition/server/migrations"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func() Store { return NewMemoryStore() })
+}
+
+func TestPostgresStore(t *testing.T) {
+	dbname := os.Getenv("TEST_DB_NAME")
+
+	if dbname == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	db, err := sql.Open("postgres", fmt.Sprintf("user=%s dbname=%s sslmode=disable", os.Getenv("DB_USER"), dbname))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	m, err := migrations.New(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, func() Store {
+		if _, err := m.Down(math.MaxInt32); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewPostgresStore(db, 5*time.Second)
+	})
+
+	m.Down(math.MaxInt32)
+}
+
+func testStore(t *testing.T, newStore func() Store) {
+	t.Run("Users", func(t *testing.T) {
+		s := newStore()
+		id, err := s.CreateUser(&User{Username: "test", Email: "test@test.com", Password: "hash"})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.CreateUser(&User{Username: "test", Email: "other@test.com"}); err != ErrUsernameTaken {
+			t.Fatalf("got %v, want ErrUsernameTaken", err)
+		}
+
+		if _, err := s.CreateUser(&User{Username: "other", Email: "test@test.com"}); err != ErrEmailTaken {
+			t.Fatalf("got %v, want ErrEmailTaken", err)
+		}
+
+		if err := s.UpdateUserPassword(id, "rehashed"); err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := s.GetUserByUsername("test")
+
+		if err != nil || u.ID != id || u.Email != "test@test.com" || u.Password != "rehashed" || !u.PasswordSet {
+			t.Fatalf("got %+v, %v", u, err)
+		}
+
+		if u, err := s.GetUserByEmail("test@test.com"); err != nil || u.ID != id {
+			t.Fatalf("got %+v, %v", u, err)
+		}
+
+		if _, err := s.GetUserByID(id + 1); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		otherID, _ := s.CreateUser(&User{Username: "other", Email: "other@test.com"})
+
+		if u, err := s.GetUserByID(otherID); err != nil || u.PasswordSet {
+			t.Fatalf("got %+v, %v", u, err)
+		}
+
+		if err := s.UpdateUserPassword(otherID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+
+		if u, err := s.GetUserByID(otherID); err != nil || !u.PasswordSet {
+			t.Fatalf("got %+v, %v", u, err)
+		}
+	})
+
+	t.Run("Articles", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+
+		for i := 1; i <= 11; i++ {
+			if _, err := s.CreateArticle(&Article{UserID: userID, Title: fmt.Sprintf("Title %d", i), Body: "Body"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, page := range []int{0, MaxPage + 1} {
+			if _, err := s.GetArticlePreviews(page); err == nil {
+				t.Fatalf("page %d: got no error", page)
+			}
+		}
+
+		first, _ := s.GetArticlePreviews(1)
+		second, _ := s.GetArticlePreviews(2)
+
+		if len(first) != 10 || len(second) != 1 || first[0].Username != "test" {
+			t.Fatalf("got %d and %d previews", len(first), len(second))
+		}
+
+		a, err := s.GetArticle(second[0].ID)
+
+		if err != nil || a.Title != "Title 1" || a.Username != "test" || a.UserID != userID {
+			t.Fatalf("got %+v, %v", a, err)
+		}
+
+		if articles, _ := s.GetArticlesByUser(userID); len(articles) != 11 || articles[0].Title != "Title 1" {
+			t.Fatalf("got %+v", articles)
+		}
+
+		if _, err := s.GetArticle(a.ID + 100); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if _, err := s.CreateArticle(&Article{UserID: userID + 1, Title: "Title", Body: "Body"}); err != ErrUnknownUser {
+			t.Fatalf("got %v, want ErrUnknownUser", err)
+		}
+	})
+
+	t.Run("ExpiredSessions", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+		past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+		for id, expiresAt := range map[string]*time.Time{"old": &past, "live": &future, "forever": nil} {
+			if err := s.CreateSession(&Session{ID: id, UserID: userID, ExpiresAt: expiresAt}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := s.GetSession("old"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if session, err := s.GetSession("live"); err != nil || session.ExpiresAt == nil {
+			t.Fatalf("got %+v, %v", session, err)
+		}
+
+		if sessions, _ := s.GetSessions(userID); len(sessions) != 2 {
+			t.Fatalf("got %+v", sessions)
+		}
+
+		later := future.Add(time.Hour)
+
+		for _, id := range []string{"old", "missing"} {
+			if err := s.ExtendSession(id, userID, &later); err != ErrNotFound {
+				t.Fatalf("%s: got %v, want ErrNotFound", id, err)
+			}
+		}
+
+		if err := s.ExtendSession("live", userID+1, &later); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.ExtendSession("live", userID, &later); err != nil {
+			t.Fatal(err)
+		}
+
+		if session, err := s.GetSession("live"); err != nil || session.ExpiresAt == nil || !session.ExpiresAt.After(future) {
+			t.Fatalf("got %+v, %v", session, err)
+		}
+
+		if n, err := s.PruneSessions(time.Now()); err != nil || n != 1 {
+			t.Fatalf("got %d, %v", n, err)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+
+		for _, id := range []string{"a", "b", "c"} {
+			if err := s.CreateSession(&Session{ID: id, UserID: userID, UserAgent: "Agent", IP: "192.0.2.1"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := s.RevokeSession("a", userID+1); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.RevokeSession("a", userID); err != nil {
+			t.Fatal(err)
+		}
+
+		if session, err := s.GetSession("a"); err != nil || !session.Revoked || session.UserID != userID {
+			t.Fatalf("got %+v, %v", session, err)
+		}
+
+		if err := s.RevokeOtherSessions(userID, "b"); err != nil {
+			t.Fatal(err)
+		}
+
+		if sessions, _ := s.GetSessions(userID); len(sessions) != 1 || sessions[0].ID != "b" {
+			t.Fatalf("got %+v", sessions)
+		}
+
+		if n, err := s.PruneSessions(time.Now().Add(time.Minute)); err != nil || n != 2 {
+			t.Fatalf("got %d, %v", n, err)
+		}
+
+		if _, err := s.GetSession("a"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if _, err := s.GetSession("b"); err != nil {
+			t.Fatal(err)
+		}
+
+		agent := "Agent \xff" + strings.Repeat("é", 300)
+
+		if err := s.CreateSession(&Session{ID: "d", UserID: userID, UserAgent: agent, IP: "192.0.2.1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions, err := s.GetSessions(userID)
+
+		if err != nil || len(sessions) != 2 {
+			t.Fatalf("got %+v, %v", sessions, err)
+		}
+
+		for _, session := range sessions {
+			if session.ID == "d" && (!utf8.ValidString(session.UserAgent) || utf8.RuneCountInString(session.UserAgent) != 255) {
+				t.Fatalf("got %q", session.UserAgent)
+			}
+		}
+	})
+
+	t.Run("APIKeys", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+		k := &APIKey{UserID: userID, Name: "CI", Prefix: "cmp_abcd", KeyHash: "hash", Scopes: []string{"articles:read", "articles:write"}}
+
+		if err := s.CreateAPIKey(k); err != nil || k.ID == 0 || k.CreatedAt.IsZero() {
+			t.Fatalf("got %+v, %v", k, err)
+		}
+
+		if err := s.TouchAPIKey(k.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := s.GetAPIKeyByHash("hash")
+
+		if err != nil || found.ID != k.ID || found.Username != "test" || len(found.Scopes) != 2 {
+			t.Fatalf("got %+v, %v", found, err)
+		}
+
+		if keys, _ := s.GetAPIKeys(userID); len(keys) != 1 || keys[0].LastUsedAt == nil {
+			t.Fatalf("got %+v", keys)
+		}
+
+		if err := s.DeleteAPIKey(k.ID, userID+1); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.DeleteAPIKey(k.ID, userID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("TwoFactor", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+
+		if _, err := s.GetTwoFactor(userID); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.SetTwoFactorSecret(userID, "SECRET"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.EnableTwoFactor(userID, []string{"one", "two"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if tf, err := s.GetTwoFactor(userID); err != nil || !tf.Enabled || tf.Secret != "SECRET" {
+			t.Fatalf("got %+v, %v", tf, err)
+		}
+
+		if err := s.UseRecoveryCode(userID, "one"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.UseRecoveryCode(userID, "one"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.UseTOTPStep(userID, 10); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.UseTOTPStep(userID, 10); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if tf, err := s.GetTwoFactor(userID); err != nil || tf.LastStep != 10 {
+			t.Fatalf("got %+v, %v", tf, err)
+		}
+
+		if err := s.CreateChallenge("a", userID, time.Now().Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.CompleteChallenge("a", userID, 10, ""); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.CompleteChallenge("a", userID, 0, "one"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if err := s.CompleteChallenge("a", userID, 11, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.CompleteChallenge("a", userID, 0, "two"); err != ErrChallengeUsed {
+			t.Fatalf("got %v, want ErrChallengeUsed", err)
+		}
+
+		if err := s.CreateChallenge("b", userID, time.Now().Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.CompleteChallenge("b", userID, 0, "two"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.CompleteChallenge("missing", userID, 12, ""); err != ErrChallengeUsed {
+			t.Fatalf("got %v, want ErrChallengeUsed", err)
+		}
+
+		if tf, err := s.GetTwoFactor(userID); err != nil || tf.LastStep != 11 {
+			t.Fatalf("got %+v, %v", tf, err)
+		}
+
+		if n, err := s.PruneChallenges(time.Now().Add(time.Hour)); err != nil || n != 2 {
+			t.Fatalf("got %d, %v", n, err)
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+		articleID, _ := s.CreateArticle(&Article{UserID: userID, Title: "Title", Body: "Body"})
+		s.CreateSession(&Session{ID: "a", UserID: userID})
+		s.CreateIdentity(&Identity{UserID: userID, Provider: "stub", Subject: "1", Email: "test@test.com"})
+
+		if err := s.CreateIdentity(&Identity{UserID: userID, Provider: "stub", Subject: "1", Email: "test@test.com"}); err != ErrIdentityTaken {
+			t.Fatalf("got %v, want ErrIdentityTaken", err)
+		}
+
+		if err := s.DeleteUser(userID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.GetArticle(articleID); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if _, err := s.GetSession("a"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+
+		if _, err := s.GetUserByIdentity("stub", "1"); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("AnonymizeUser", func(t *testing.T) {
+		s := newStore()
+		userID, _ := s.CreateUser(&User{Username: "test", Email: "test@test.com"})
+		articleID, _ := s.CreateArticle(&Article{UserID: userID, Title: "Title", Body: "Body"})
+
+		if err := s.AnonymizeUser(userID); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := s.GetArticle(articleID)
+		want := fmt.Sprintf("deleted-%d", userID)
+
+		if err != nil || a.Username != want {
+			t.Fatalf("got %+v, %v", a, err)
+		}
+
+		// Anonymized names are reserved so that anonymizing cannot collide.
+		if _, err := s.CreateUser(&User{Username: fmt.Sprintf("deleted-%d", userID+1), Email: "next@test.com"}); err != ErrUsernameTaken {
+			t.Fatalf("got %v, want ErrUsernameTaken", err)
+		}
+
+		nextID, _ := s.CreateUser(&User{Username: "next", Email: "next@test.com"})
+
+		if err := s.AnonymizeUser(nextID); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/richardpanda/composition/server/api/models")
+
+func exec(ctx context.Context, q Querier, name, statement string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(ctx, name, statement)
+	defer span.End()
+
+	result, err := q.ExecContext(ctx, statement, args...)
+	recordError(span, err)
+
+	return result, err
+}
+
+func query(ctx context.Context, q Querier, name, statement string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, name, statement)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, statement, args...)
+	recordError(span, err)
+
+	return rows, err
+}
+
+func queryRow(ctx context.Context, q Querier, name, statement string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, name, statement)
+	defer span.End()
+
+	row := q.QueryRowContext(ctx, statement, args...)
+
+	if err := row.Err(); err != sql.ErrNoRows {
+		recordError(span, err)
+	}
+
+	return row
+}
+
+func startSpan(ctx context.Context, name, statement string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", name),
+			attribute.String("db.statement", strings.Join(strings.Fields(statement), " ")),
+		),
+	)
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
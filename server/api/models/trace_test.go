@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeRows struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeRows) Columns() []string         { return []string{"id"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("fake", fakeDriver{})
+}
+
+func TestQueriesAreTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	db, err := sql.Open("fake", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	store := NewPostgresStore(db, time.Second).WithContext(ctx)
+
+	if _, err := store.GetArticle(1); err != ErrNotFound {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+
+	if err := store.TouchSession("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+
+	for i, name := range []string{"GetArticle", "TouchSession"} {
+		span := spans[i]
+
+		if span.Name != name || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("got %s with parent %s", span.Name, span.Parent.SpanID())
+		}
+
+		found := false
+
+		for _, attr := range span.Attributes {
+			if attr.Key == attribute.Key("db.operation") && attr.Value.AsString() == name {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatalf("%s has attributes %v", name, span.Attributes)
+		}
+	}
+}
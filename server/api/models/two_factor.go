@@ -0,0 +1,99 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type TwoFactor struct {
+	Secret  string
+	Enabled bool
+	// LastStep is the TOTP time step of the last accepted code.
+	LastStep int64
+}
+
+const enableTwoFactorQuery = "UPDATE two_factor SET enabled = TRUE WHERE user_id = $1;"
+const getTwoFactorQuery = "SELECT secret, enabled, last_step FROM two_factor WHERE user_id = $1;"
+const setTwoFactorSecretQuery = `
+	INSERT INTO two_factor (user_id, secret, enabled) VALUES ($1, $2, FALSE)
+	ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled = FALSE;
+`
+const useTOTPStepQuery = `
+	UPDATE two_factor SET last_step = $2
+	WHERE user_id = $1 AND last_step < $2;
+`
+
+const createChallengeQuery = "INSERT INTO challenges (id, user_id, expires_at) VALUES ($1, $2, $3);"
+const pruneChallengesQuery = "DELETE FROM challenges WHERE expires_at < $1;"
+const useChallengeQuery = `
+	UPDATE challenges SET used_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW();
+`
+
+const createRecoveryCodeQuery = "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2);"
+const deleteRecoveryCodesQuery = "DELETE FROM recovery_codes WHERE user_id = $1;"
+const useRecoveryCodeQuery = `
+	UPDATE recovery_codes SET used_at = NOW()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	RETURNING id;
+`
+
+func EnableTwoFactor(ctx context.Context, q Querier, userID int) (sql.Result, error) {
+	return exec(ctx, q, "EnableTwoFactor", enableTwoFactorQuery, userID)
+}
+
+func GetTwoFactor(ctx context.Context, q Querier, userID int) *sql.Row {
+	return queryRow(ctx, q, "GetTwoFactor", getTwoFactorQuery, userID)
+}
+
+func SetTwoFactorSecret(ctx context.Context, q Querier, userID int, secret string) (sql.Result, error) {
+	return exec(ctx, q, "SetTwoFactorSecret", setTwoFactorSecretQuery, userID, secret)
+}
+
+func UseTOTPStep(ctx context.Context, q Querier, userID int, step int64) (sql.Result, error) {
+	return exec(ctx, q, "UseTOTPStep", useTOTPStepQuery, userID, step)
+}
+
+func CreateChallenge(ctx context.Context, q Querier, id string, userID int, expiresAt time.Time) (sql.Result, error) {
+	return exec(ctx, q, "CreateChallenge", createChallengeQuery, id, userID, expiresAt)
+}
+
+func PruneChallenges(ctx context.Context, q Querier, before time.Time) (sql.Result, error) {
+	return exec(ctx, q, "PruneChallenges", pruneChallengesQuery, before)
+}
+
+func UseChallenge(ctx context.Context, q Querier, id string, userID int) (sql.Result, error) {
+	return exec(ctx, q, "UseChallenge", useChallengeQuery, id, userID)
+}
+
+// CompleteChallenge uses a challenge and then a TOTP step, or a recovery code
+// when step is zero. Run it in a transaction so a rejected code leaves the
+// challenge unused and a used challenge consumes no code.
+func CompleteChallenge(ctx context.Context, q Querier, id string, userID int, step int64, codeHash string) error {
+	if err := affected(UseChallenge(ctx, q, id, userID)); err != nil {
+		if err == ErrNotFound {
+			return ErrChallengeUsed
+		}
+		return err
+	}
+
+	if step > 0 {
+		return affected(UseTOTPStep(ctx, q, userID, step))
+	}
+
+	var codeID int
+	return notFound(UseRecoveryCode(ctx, q, userID, codeHash).Scan(&codeID))
+}
+
+func CreateRecoveryCode(ctx context.Context, q Querier, userID int, codeHash string) (sql.Result, error) {
+	return exec(ctx, q, "CreateRecoveryCode", createRecoveryCodeQuery, userID, codeHash)
+}
+
+func DeleteRecoveryCodes(ctx context.Context, q Querier, userID int) (sql.Result, error) {
+	return exec(ctx, q, "DeleteRecoveryCodes", deleteRecoveryCodesQuery, userID)
+}
+
+func UseRecoveryCode(ctx context.Context, q Querier, userID int, codeHash string) *sql.Row {
+	return queryRow(ctx, q, "UseRecoveryCode", useRecoveryCodeQuery, userID, codeHash)
+}
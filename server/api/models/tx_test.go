@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+type txDriver struct {
+	mu         sync.Mutex
+	commits    int
+	rollbacks  int
+	commitErrs []error
+}
+
+type txConn struct {
+	fakeConn
+	d *txDriver
+}
+
+type fakeTx struct {
+	d *txDriver
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return txConn{d: d}, nil }
+
+func (c txConn) Begin() (driver.Tx, error) { return fakeTx{c.d}, nil }
+
+func (tx fakeTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+
+	tx.d.commits++
+
+	if len(tx.d.commitErrs) > 0 {
+		err := tx.d.commitErrs[0]
+		tx.d.commitErrs = tx.d.commitErrs[1:]
+		return err
+	}
+
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+
+	tx.d.rollbacks++
+	return nil
+}
+
+func openTx(t *testing.T, commitErrs ...error) (*sql.DB, *txDriver) {
+	d := &txDriver{commitErrs: commitErrs}
+	sql.Register(t.Name(), d)
+
+	db, err := sql.Open(t.Name(), "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db, d
+}
+
+func TestTransactCommits(t *testing.T) {
+	db, d := openTx(t)
+	ctx := context.Background()
+
+	err := Transact(ctx, db, func(tx *sql.Tx) error {
+		_, err := TouchSession(ctx, tx, "a")
+		return err
+	})
+
+	if err != nil || d.commits != 1 || d.rollbacks != 0 {
+		t.Fatalf("got %v with %d commits and %d rollbacks", err, d.commits, d.rollbacks)
+	}
+}
+
+func TestTransactRollsBack(t *testing.T) {
+	db, d := openTx(t)
+	want := errors.New("failed")
+
+	err := Transact(context.Background(), db, func(tx *sql.Tx) error {
+		return want
+	})
+
+	if err != want || d.commits != 0 || d.rollbacks != 1 {
+		t.Fatalf("got %v with %d commits and %d rollbacks", err, d.commits, d.rollbacks)
+	}
+}
+
+func TestTransactRetriesSerializationFailures(t *testing.T) {
+	db, d := openTx(t, &pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"})
+	calls := 0
+
+	err := Transact(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return nil
+	})
+
+	if err != nil || calls != 3 || d.commits != 3 {
+		t.Fatalf("got %v after %d calls and %d commits", err, calls, d.commits)
+	}
+}
+
+func TestTransactGivesUp(t *testing.T) {
+	failure := &pq.Error{Code: "40001"}
+	db, _ := openTx(t, failure, failure, failure, failure)
+	calls := 0
+
+	err := Transact(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return nil
+	})
+
+	if err != failure || calls != maxTransactAttempts {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+}
@@ -1,39 +1,42 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 )
 
 type User struct {
+	ID       int
 	Username string
 	Email    string
 	Password string
+	// PasswordSet is false for accounts created through OIDC, which have no
+	// password until the user sets one. It is derived from Password on create.
+	PasswordSet bool
 }
 
-const createUserQuery = "INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id;"
-const createUsersTableQuery = `
-	CREATE TABLE IF NOT EXISTS users (
-		id       SERIAL       PRIMARY KEY,
-		username VARCHAR(20)  UNIQUE NOT NULL,
-		email    VARCHAR(50)  UNIQUE NOT NULL,
-		password VARCHAR(255) NOT NULL
-	);
-`
-const dropUserTableQuery = "DROP TABLE users CASCADE;"
-const getUserByUsernameQuery = "SELECT * FROM users WHERE username=$1;"
-
-func CreateUser(db *sql.DB, u *User) *sql.Row {
-	return db.QueryRow(createUserQuery, u.Username, u.Email, u.Password)
+const createUserQuery = "INSERT INTO users (username, email, password, password_set) VALUES ($1, $2, $3, $4) RETURNING id;"
+const getUserByEmailQuery = "SELECT id, username FROM users WHERE email=$1;"
+const getUserByIDQuery = "SELECT id, username, email, password, password_set FROM users WHERE id=$1;"
+const getUserByUsernameQuery = "SELECT id, username, email, password, password_set FROM users WHERE username=$1;"
+const updateUserPasswordQuery = "UPDATE users SET password=$2, password_set=TRUE WHERE id=$1;"
+
+func CreateUser(ctx context.Context, q Querier, u *User) *sql.Row {
+	return queryRow(ctx, q, "CreateUser", createUserQuery, u.Username, u.Email, u.Password, u.Password != "")
+}
+
+func GetUserByID(ctx context.Context, q Querier, id int) *sql.Row {
+	return queryRow(ctx, q, "GetUserByID", getUserByIDQuery, id)
 }
 
-func CreateUsersTable(db *sql.DB) (sql.Result, error) {
-	return db.Exec(createUsersTableQuery)
+func GetUserByUsername(ctx context.Context, q Querier, username string) *sql.Row {
+	return queryRow(ctx, q, "GetUserByUsername", getUserByUsernameQuery, username)
 }
 
-func DropUsersTable(db *sql.DB) (sql.Result, error) {
-	return db.Exec(dropUserTableQuery)
+func GetUserByEmail(ctx context.Context, q Querier, email string) *sql.Row {
+	return queryRow(ctx, q, "GetUserByEmail", getUserByEmailQuery, email)
 }
 
-func GetUserByUsername(db *sql.DB, username string) *sql.Row {
-	return db.QueryRow(getUserByUsernameQuery, username)
+func UpdateUserPassword(ctx context.Context, q Querier, id int, hash string) (sql.Result, error) {
+	return exec(ctx, q, "UpdateUserPassword", updateUserPasswordQuery, id, hash)
 }
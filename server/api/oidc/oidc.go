@@ -0,0 +1,364 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/config"
+)
+
+const keyRefreshInterval = time.Minute
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+type Provider struct {
+	Config
+	Client *http.Client
+
+	mu          sync.Mutex
+	discovery   *discovery
+	keys        map[string]jwk
+	lastRefresh time.Time
+}
+
+type Providers map[string]*Provider
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	key    crypto.PublicKey
+	method jwt.SigningMethod
+}
+
+func NewProvider(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		Config: cfg,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewProviders creates a provider for each configured entry.
+func NewProviders(cfg config.OIDC) Providers {
+	providers := Providers{}
+
+	for name, p := range cfg.Providers {
+		providers[name] = NewProvider(Config{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	return providers
+}
+
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) (string, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.ClientID)
+	v.Set("redirect_uri", p.RedirectURL)
+	v.Set("scope", strings.Join(p.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+func (p *Provider) Exchange(code, verifier string) (string, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", p.RedirectURL)
+	v.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest("POST", d.TokenEndpoint, strings.NewReader(v.Encode()))
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := p.Client.Do(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: %s: decoding token response: %v", p.Name, err)
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("oidc: %s: token endpoint returned %d: %s %s", p.Name, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	if body.IDToken == "" {
+		return "", fmt.Errorf("oidc: %s: token response has no id_token", p.Name)
+	}
+
+	return body.IDToken, nil
+}
+
+func (p *Provider) VerifyIDToken(raw, nonce string) (*Identity, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	t, err := jwt.ParseWithClaims(raw, claims, p.keyfunc)
+
+	if err != nil || !t.Valid {
+		return nil, fmt.Errorf("oidc: %s: invalid id token: %v", p.Name, err)
+	}
+
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return nil, fmt.Errorf("oidc: %s: unexpected issuer", p.Name)
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("oidc: %s: id token has no expiry", p.Name)
+	}
+
+	if !hasAudience(claims["aud"], p.ClientID) {
+		return nil, fmt.Errorf("oidc: %s: unexpected audience", p.Name)
+	}
+
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, fmt.Errorf("oidc: %s: nonce mismatch", p.Name)
+	}
+
+	sub, _ := claims["sub"].(string)
+
+	if sub == "" {
+		return nil, fmt.Errorf("oidc: %s: id token has no subject", p.Name)
+	}
+
+	id := &Identity{Subject: sub}
+	id.Email, _ = claims["email"].(string)
+	id.PreferredUsername, _ = claims["preferred_username"].(string)
+
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+
+	return id, nil
+}
+
+func (p *Provider) discover() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &discovery{}
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+
+	if err := p.getJSON(wellKnown, d); err != nil {
+		return nil, err
+	}
+
+	if d.Issuer != p.Issuer {
+		return nil, fmt.Errorf("oidc: %s: discovery issuer %q does not match %q", p.Name, d.Issuer, p.Issuer)
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: %s: incomplete discovery document", p.Name)
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+func (p *Provider) keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	k, ok := p.cachedKey(kid)
+
+	if !ok {
+		if err := p.refreshKeys(); err != nil {
+			return nil, err
+		}
+
+		if k, ok = p.cachedKey(kid); !ok {
+			return nil, fmt.Errorf("oidc: %s: unknown kid %q", p.Name, kid)
+		}
+	}
+
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("oidc: %s: unexpected signing method %q", p.Name, t.Method.Alg())
+	}
+
+	return k.key, nil
+}
+
+func (p *Provider) cachedKey(kid string) (jwk, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) refreshKeys() error {
+	p.mu.Lock()
+	if time.Since(p.lastRefresh) < keyRefreshInterval {
+		p.mu.Unlock()
+		return nil
+	}
+	p.lastRefresh = time.Now()
+	p.mu.Unlock()
+
+	d, err := p.discover()
+
+	if err != nil {
+		return err
+	}
+
+	var body types.JWKSResponseBody
+
+	if err := p.getJSON(d.JWKSURI, &body); err != nil {
+		return err
+	}
+
+	ks := map[string]jwk{}
+
+	for _, k := range body.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, method, err := keys.ParseJWK(k)
+
+		if err != nil {
+			continue
+		}
+
+		ks[k.Kid] = jwk{key: key, method: method}
+	}
+
+	p.mu.Lock()
+	p.keys = ks
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *Provider) getJSON(u string, v interface{}) error {
+	resp, err := p.Client.Get(u)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("oidc: %s: GET %s returned %d", p.Name, u, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, _ := a.(string); s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package openapi
+
+import (
+	_ "embed"
+)
+
+// Spec is the OpenAPI 3 document describing every route in router.New.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Docs is a self-contained page that renders Spec from ./openapi.json.
+//
+//go:embed docs.html
+var Docs []byte
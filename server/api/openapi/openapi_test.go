@@ -0,0 +1,235 @@
+package openapi
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// Structs in api/types that never appear on the wire.
+var internal = map[string]bool{
+	"JWTClaims":       true,
+	"OIDCStateClaims": true,
+	"TokenLifetimes":  true,
+}
+
+var documented = map[string]interface{}{
+	"APIKey":                       types.APIKey{},
+	"ArticlePreview":               types.ArticlePreview{},
+	"ChangePasswordRequestBody":    types.ChangePasswordRequestBody{},
+	"CheckResult":                  types.CheckResult{},
+	"DeleteMeRequestBody":          types.DeleteMeRequestBody{},
+	"ErrorResponseBody":            types.ErrorResponseBody{},
+	"Export":                       types.Export{},
+	"ExportArticle":                types.ExportArticle{},
+	"ExportIdentity":               types.ExportIdentity{},
+	"ExportProfile":                types.ExportProfile{},
+	"FieldError":                   types.FieldError{},
+	"GetAPIKeysResponseBody":       types.GetAPIKeysResponseBody{},
+	"GetArticleResponseBody":       types.GetArticleResponseBody{},
+	"GetArticlesResponseBody":      types.GetArticlesResponseBody{},
+	"GetSessionsResponseBody":      types.GetSessionsResponseBody{},
+	"HealthResponseBody":           types.HealthResponseBody{},
+	"JWK":                          types.JWK{},
+	"JWKSResponseBody":             types.JWKSResponseBody{},
+	"PostAPIKeysRequestBody":       types.PostAPIKeysRequestBody{},
+	"PostAPIKeysResponseBody":      types.PostAPIKeysResponseBody{},
+	"PostArticlesRequestBody":      types.PostArticlesRequestBody{},
+	"PostArticlesResponseBody":     types.PostArticlesResponseBody{},
+	"PostTokenResponseBody":        types.PostTokenResponseBody{},
+	"ReadinessResponseBody":        types.ReadinessResponseBody{},
+	"Session":                      types.Session{},
+	"SigninChallengeResponseBody":  types.SigninChallengeResponseBody{},
+	"SigninRequestBody":            types.SigninRequestBody{},
+	"SigninResponseBody":           types.SigninResponseBody{},
+	"SigninTwoFactorRequestBody":   types.SigninTwoFactorRequestBody{},
+	"SignupRequestBody":            types.SignupRequestBody{},
+	"SignupResponseBody":           types.SignupResponseBody{},
+	"TwoFactorConfirmRequestBody":  types.TwoFactorConfirmRequestBody{},
+	"TwoFactorConfirmResponseBody": types.TwoFactorConfirmResponseBody{},
+	"TwoFactorSetupResponseBody":   types.TwoFactorSetupResponseBody{},
+}
+
+// Query strings, documented as parameters rather than schemas.
+var queries = map[string]interface{}{
+	"GetArticlesQuery": types.GetArticlesQuery{},
+}
+
+type field struct {
+	name     string
+	typ      string
+	required bool
+}
+
+func load(t *testing.T) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(Spec)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+func TestSpecIsValid(t *testing.T) {
+	if err := load(t).Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEveryTypeIsDocumented(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "../types/types.go", nil, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+
+			if _, ok := ts.Type.(*ast.StructType); !ok || internal[ts.Name.Name] {
+				continue
+			}
+
+			if _, ok := queries[ts.Name.Name]; ok {
+				continue
+			}
+
+			if _, ok := documented[ts.Name.Name]; !ok {
+				t.Errorf("types.%s has no schema", ts.Name.Name)
+			}
+		}
+	}
+}
+
+func TestParametersMatchQueries(t *testing.T) {
+	params := map[string]bool{}
+
+	for _, ref := range load(t).Components.Parameters {
+		if ref.Value.In == "query" {
+			params[ref.Value.Name] = true
+		}
+	}
+
+	for name, v := range queries {
+		rt := reflect.TypeOf(v)
+
+		for i := 0; i < rt.NumField(); i++ {
+			if p := rt.Field(i).Tag.Get("form"); !params[p] {
+				t.Errorf("%s.%s has no query parameter %q", name, rt.Field(i).Name, p)
+			}
+		}
+	}
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	schemas := load(t).Components.Schemas
+
+	if len(schemas) != len(documented) {
+		t.Errorf("got %d schemas, want %d", len(schemas), len(documented))
+	}
+
+	for name, v := range documented {
+		ref, ok := schemas[name]
+
+		if !ok {
+			t.Errorf("%s is missing", name)
+			continue
+		}
+
+		request := strings.HasSuffix(name, "RequestBody")
+		got := schemaFields(ref.Value)
+		want := structFields(reflect.TypeOf(v), request)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s\ngot:  %v\nwant: %v", name, got, want)
+		}
+	}
+}
+
+func schemaFields(s *openapi3.Schema) []field {
+	required := map[string]bool{}
+
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	fields := []field{}
+
+	for name, prop := range s.Properties {
+		fields = append(fields, field{name, prop.Value.Type.Slice()[0], required[name]})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	return fields
+}
+
+// structFields lists the JSON fields of t. Response fields are always
+// present unless omitempty; request fields are required when validated so.
+func structFields(t reflect.Type, request bool) []field {
+	fields := []field{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous {
+			fields = append(fields, structFields(f.Type, request)...)
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		required := len(tag) == 1
+
+		if request {
+			required = strings.Contains(f.Tag.Get("binding"), "required")
+		}
+
+		fields = append(fields, field{tag[0], jsonType(f.Type), required})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	return fields
+}
+
+func jsonType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "array"
+	default:
+		return "object"
+	}
+}
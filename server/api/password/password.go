@@ -0,0 +1,95 @@
+package password
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const bcryptMaxBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var common = map[string]bool{}
+
+func init() {
+	for _, p := range strings.Split(commonPasswordList, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			common[strings.ToLower(p)] = true
+		}
+	}
+}
+
+type Policy struct {
+	MinLength int
+	MaxBytes  int
+	Cost      int
+
+	dummyOnce sync.Once
+	dummyHash []byte
+}
+
+func NewPolicy(minLength, cost int) (*Policy, error) {
+	if minLength < 1 || minLength > bcryptMaxBytes {
+		return nil, fmt.Errorf("password: minimum length must be between 1 and %d", bcryptMaxBytes)
+	}
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &Policy{MinLength: minLength, MaxBytes: bcryptMaxBytes, Cost: cost}, nil
+}
+
+func (p *Policy) Validate(password, username, email string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters.", p.MinLength)
+	}
+
+	if len(password) > p.MaxBytes {
+		return fmt.Errorf("Password must be at most %d bytes.", p.MaxBytes)
+	}
+
+	lower := strings.ToLower(password)
+
+	if common[lower] {
+		return errors.New("Password is too common.")
+	}
+
+	for _, name := range []string{username, strings.SplitN(email, "@", 2)[0]} {
+		name = strings.ToLower(name)
+
+		if len(name) >= 3 && (strings.Contains(lower, name) || strings.Contains(name, lower)) {
+			return errors.New("Password is too similar to your username or email.")
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.Cost)
+	return string(hash), err
+}
+
+func (p *Policy) Compare(hash, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+func (p *Policy) CompareDummy(password string) {
+	p.dummyOnce.Do(func() {
+		p.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("composition"), p.Cost)
+	})
+
+	bcrypt.CompareHashAndPassword(p.dummyHash, []byte(password))
+}
+
+func (p *Policy) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost != p.Cost
+}
@@ -0,0 +1,68 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewPolicyRejectsInvalidSettings(t *testing.T) {
+	if _, err := NewPolicy(0, bcrypt.MinCost); err == nil {
+		t.Fatal("expected error for zero minimum length")
+	}
+
+	if _, err := NewPolicy(73, bcrypt.MinCost); err == nil {
+		t.Fatal("expected error for minimum length over 72")
+	}
+
+	if _, err := NewPolicy(8, bcrypt.MaxCost+1); err == nil {
+		t.Fatal("expected error for cost over the bcrypt maximum")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p, _ := NewPolicy(8, bcrypt.MinCost)
+
+	cases := []struct {
+		password string
+		valid    bool
+	}{
+		{"correct horse battery", true},
+		{"ünïcödé!", true},
+		{"short", false},
+		{"PASSWORD123", false},
+		{"alice-rocks", false},
+		{"mail.alice2", false},
+	}
+
+	for _, tc := range cases {
+		err := p.Validate(tc.password, "alice", "alice@example.com")
+
+		if (err == nil) != tc.valid {
+			t.Fatalf("Validate(%q) = %v, want valid %v", tc.password, err, tc.valid)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	p, _ := NewPolicy(8, bcrypt.MinCost+1)
+	old, _ := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+
+	if !p.NeedsRehash(string(old)) {
+		t.Fatal("expected hash with a different cost to need rehashing")
+	}
+
+	hash, err := p.Hash("correct horse battery")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.NeedsRehash(hash) {
+		t.Fatal("expected hash with the configured cost not to need rehashing")
+	}
+
+	if err := p.Compare(hash, "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+}
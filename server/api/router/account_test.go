@@ -0,0 +1,170 @@
+package router
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func deleteMe(r http.Handler, ss, password string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(types.DeleteMeRequestBody{Password: password})
+
+	req, _ := http.NewRequest("DELETE", "/api/me", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestGetExport(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+
+	for i := 1; i <= 2; i++ {
+		store.CreateArticle(&models.Article{
+			UserID: id,
+			Title:  fmt.Sprintf("Title %d", i),
+			Body:   fmt.Sprintf("Body %d", i),
+		})
+	}
+
+	req, _ := http.NewRequest("GET", "/api/me/export", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, rr.Header().Get("Content-Type"), "application/zip")
+	assertEqual(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment; "), true)
+
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+
+	assertEqual(t, err, nil)
+
+	export := &types.Export{}
+	markdown := 0
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+
+		assertEqual(t, err, nil)
+
+		b, err := io.ReadAll(rc)
+		rc.Close()
+
+		assertEqual(t, err, nil)
+
+		switch {
+		case f.Name == "export.json":
+			assertEqual(t, json.Unmarshal(b, export), nil)
+		case strings.HasPrefix(f.Name, "articles/") && strings.HasSuffix(f.Name, ".md"):
+			assertEqual(t, strings.HasPrefix(string(b), "# Title "), true)
+			markdown++
+		}
+	}
+
+	assertEqual(t, export.Profile.ID, id)
+	assertEqual(t, export.Profile.Username, "test")
+	assertEqual(t, export.Profile.Email, "test@test.com")
+	assertEqual(t, len(export.Articles), 2)
+	assertEqual(t, len(export.Sessions), 1)
+	assertEqual(t, markdown, 2)
+}
+
+func TestDeleteMeWithInvalidPassword(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	rr := deleteMe(router, ss, "invalid password")
+
+	assertEqual(t, rr.Code, 400)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Password is invalid.")
+}
+
+func TestDeleteMe(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+
+	articleID, err := store.CreateArticle(&models.Article{UserID: id, Title: "Title", Body: "Body"})
+
+	assertEqual(t, err, nil)
+	assertEqual(t, deleteMe(router, ss, "test").Code, 204)
+
+	_, err = store.GetUserByID(id)
+
+	assertEqual(t, err, models.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles/%d", articleID), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+}
+
+func TestDeleteMeWithAnonymizePolicy(t *testing.T) {
+	resetStore()
+
+	opts := options
+	opts.DeletionPolicy = models.DeletionPolicyAnonymize
+	anonymizing := New(store, opts)
+	id, ss := createUserWithToken(t)
+
+	articleID, err := store.CreateArticle(&models.Article{UserID: id, Title: "Title", Body: "Body"})
+
+	assertEqual(t, err, nil)
+	assertEqual(t, deleteMe(anonymizing, ss, "test").Code, 204)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles/%d", articleID), nil)
+	rr := httptest.NewRecorder()
+	anonymizing.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	r := &types.GetArticleResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), r)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, r.Username, fmt.Sprintf("deleted-%d", id))
+	assertEqual(t, postArticleWithToken(ss).Code, 401)
+}
+
+func TestDeleteMeWithoutPassword(t *testing.T) {
+	resetStore()
+
+	rr := oidcSignin(t, stubIdentity{Subject: "1234", Email: "oidc@test.com", EmailVerified: true}, nil)
+
+	assertEqual(t, rr.Code, 200)
+
+	respBody := &types.SigninResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	stale := options
+	stale.TokenLifetimes.Reauthentication = time.Nanosecond
+
+	rr = deleteMe(New(store, stale), respBody.Token, "")
+
+	assertEqual(t, rr.Code, 403)
+	assertProblemHeader(t, rr)
+	assertEqual(t, deleteMe(router, respBody.Token, "").Code, 204)
+}
@@ -0,0 +1,198 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/apikey"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func createAPIKey(t *testing.T, ss string, scopes ...string) *types.PostAPIKeysResponseBody {
+	b, _ := json.Marshal(types.PostAPIKeysRequestBody{
+		Name:   "CI",
+		Scopes: scopes,
+	})
+
+	req, _ := http.NewRequest("POST", "/api/me/api-keys", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 201)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.PostAPIKeysResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	return respBody
+}
+
+func postArticleWithKey(key string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(types.PostArticlesRequestBody{
+		Title: "Lorem Ipsum",
+		Body:  "Lorem ipsum dolor sit amet.",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/articles", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestSuccessfulPostAPIKeys(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	k := createAPIKey(t, ss, types.ScopeArticlesWrite)
+
+	assertEqual(t, k.Name, "CI")
+	assertEqual(t, apikey.IsKey(k.Key), true)
+	assertEqual(t, k.Key[:len(k.Prefix)], k.Prefix)
+	assertEqual(t, len(k.Scopes), 1)
+}
+
+func TestPostAPIKeysWithInvalidScope(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	b, _ := json.Marshal(types.PostAPIKeysRequestBody{
+		Name:   "CI",
+		Scopes: []string{types.ScopeAccount},
+	})
+
+	req, _ := http.NewRequest("POST", "/api/me/api-keys", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 400)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Scope \"account\" is invalid.")
+}
+
+func TestGetAPIKeysRecordsLastUse(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	k := createAPIKey(t, ss, types.ScopeArticlesWrite)
+
+	assertEqual(t, postArticleWithKey(k.Key).Code, 201)
+
+	req, _ := http.NewRequest("GET", "/api/me/api-keys", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.GetAPIKeysResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, len(respBody.APIKeys), 1)
+	assertEqual(t, respBody.APIKeys[0].Prefix, k.Prefix)
+	assertEqual(t, respBody.APIKeys[0].LastUsedAt != nil, true)
+}
+
+func TestPostArticlesWithReadOnlyAPIKey(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	k := createAPIKey(t, ss, types.ScopeArticlesRead)
+	rr := postArticleWithKey(k.Key)
+
+	assertEqual(t, rr.Code, 403)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Insufficient scope.")
+}
+
+func TestAPIKeyCannotManageAccount(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	k := createAPIKey(t, ss, types.ScopeArticlesRead, types.ScopeArticlesWrite)
+
+	req, _ := http.NewRequest("GET", "/api/me/api-keys", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", k.Key))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 403)
+}
+
+func TestPostArticlesWithExpiredAPIKey(t *testing.T) {
+	resetStore()
+
+	id, _ := createUserWithToken(t)
+	key, prefix, err := apikey.Generate()
+
+	assertEqual(t, err, nil)
+
+	expiresAt := time.Now().Add(-time.Hour)
+
+	err = store.CreateAPIKey(&models.APIKey{
+		UserID:    id,
+		Name:      "Expired",
+		Prefix:    prefix,
+		KeyHash:   apikey.Hash(key),
+		Scopes:    []string{types.ScopeArticlesWrite},
+		ExpiresAt: &expiresAt,
+	})
+
+	assertEqual(t, err, nil)
+
+	rr := postArticleWithKey(key)
+
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "API key has expired.")
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	k := createAPIKey(t, ss, types.ScopeArticlesWrite)
+
+	endpoint := fmt.Sprintf("/api/me/api-keys/%d", k.ID)
+	req, _ := http.NewRequest("DELETE", endpoint, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 204)
+	assertEqual(t, postArticleWithKey(k.Key).Code, 401)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+}
@@ -9,16 +9,14 @@ import (
 	"testing"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/keys"
 	"github.com/richardpanda/composition/server/api/models"
 	"github.com/richardpanda/composition/server/api/types"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func TestGetArticleWithExistentArticle(t *testing.T) {
-	createUsersTable()
-	createArticlesTable()
-	defer dropUsersTable()
-	defer dropArticlesTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -31,8 +29,7 @@ func TestGetArticleWithExistentArticle(t *testing.T) {
 		Password: string(hash),
 	}
 
-	var userID int
-	err = models.CreateUser(db, u).Scan(&userID)
+	userID, err := store.CreateUser(u)
 
 	assertEqual(t, err, nil)
 
@@ -42,8 +39,7 @@ func TestGetArticleWithExistentArticle(t *testing.T) {
 		Body:   "Body",
 	}
 
-	var articleID int
-	err = models.CreateArticle(db, a).Scan(&articleID)
+	articleID, err := store.CreateArticle(a)
 
 	endpoint := fmt.Sprintf("/api/articles/%d", articleID)
 	req, _ := http.NewRequest("GET", endpoint, nil)
@@ -64,10 +60,7 @@ func TestGetArticleWithExistentArticle(t *testing.T) {
 }
 
 func TestGetArticleWithNonexistentArticle(t *testing.T) {
-	createUsersTable()
-	createArticlesTable()
-	defer dropUsersTable()
-	defer dropArticlesTable()
+	resetStore()
 
 	endpoint := fmt.Sprintf("/api/articles/1")
 	req, _ := http.NewRequest("GET", endpoint, nil)
@@ -75,7 +68,7 @@ func TestGetArticleWithNonexistentArticle(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 404)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	r := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), r)
@@ -85,10 +78,7 @@ func TestGetArticleWithNonexistentArticle(t *testing.T) {
 }
 
 func TestGetArticlePreviews(t *testing.T) {
-	createUsersTable()
-	createArticlesTable()
-	defer dropUsersTable()
-	defer dropArticlesTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -101,13 +91,12 @@ func TestGetArticlePreviews(t *testing.T) {
 		Password: string(hash),
 	}
 
-	var id int
-	err = models.CreateUser(db, u).Scan(&id)
+	id, err := store.CreateUser(u)
 
 	assertEqual(t, err, nil)
 
 	for i := 1; i <= 11; i++ {
-		models.CreateArticle(db, &models.Article{
+		store.CreateArticle(&models.Article{
 			UserID: id,
 			Title:  fmt.Sprintf("Title %d", i),
 			Body:   fmt.Sprintf("Body %d", i),
@@ -135,10 +124,7 @@ func TestGetArticlePreviews(t *testing.T) {
 }
 
 func TestSuccessfulPostArticles(t *testing.T) {
-	createUsersTable()
-	createArticlesTable()
-	defer dropUsersTable()
-	defer dropArticlesTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -151,22 +137,11 @@ func TestSuccessfulPostArticles(t *testing.T) {
 		Password: string(hash),
 	}
 
-	var id int
-	err = models.CreateUser(db, u).Scan(&id)
+	id, err := store.CreateUser(u)
 
 	assertEqual(t, err, nil)
 
-	c := types.JWTClaims{
-		ID:       id,
-		Username: u.Username,
-		StandardClaims: jwt.StandardClaims{
-			Issuer: "Composition",
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	ss, err := token.SignedString(types.JWTSecret)
-
-	assertEqual(t, err, nil)
+	ss := newSessionToken(t, id, u.Username)
 
 	b, _ := json.Marshal(types.PostArticlesRequestBody{
 		Title: "Lorem Ipsum",
@@ -200,7 +175,7 @@ func TestPostArticlesWithoutToken(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 401)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -219,9 +194,8 @@ func TestPostArticlesWithInvalidToken(t *testing.T) {
 			Issuer: "Composition",
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	wrongSecret := append(types.JWTSecret, 'a')
-	ss, err := token.SignedString(wrongSecret)
+	wrongKey := mustGenerateKey(keys.GenerateEd25519(activeKey.ID, keys.StateActive))
+	ss, err := mustNewKeySet(wrongKey).Sign(c)
 
 	assertEqual(t, err, nil)
 
@@ -236,8 +210,8 @@ func TestPostArticlesWithInvalidToken(t *testing.T) {
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
-	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err = json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -247,19 +221,9 @@ func TestPostArticlesWithInvalidToken(t *testing.T) {
 }
 
 func TestPostArticlesWithoutTitle(t *testing.T) {
-	id := 1
-	username := "test"
-	c := types.JWTClaims{
-		ID:       id,
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			Issuer: "Composition",
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	ss, err := token.SignedString(types.JWTSecret)
+	resetStore()
 
-	assertEqual(t, err, nil)
+	_, ss := createUserWithToken(t)
 
 	b, _ := json.Marshal(types.PostArticlesRequestBody{
 		Body: "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur. Excepteur sint occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim id est laborum.",
@@ -272,29 +236,19 @@ func TestPostArticlesWithoutTitle(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
-	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
 
 	assertEqual(t, err, nil)
 	assertEqual(t, respBody.Message, "Title is required.")
 }
 
 func TestPostArticlesWithoutBody(t *testing.T) {
-	id := 1
-	username := "test"
-	c := types.JWTClaims{
-		ID:       id,
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			Issuer: "Composition",
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	ss, err := token.SignedString(types.JWTSecret)
+	resetStore()
 
-	assertEqual(t, err, nil)
+	_, ss := createUserWithToken(t)
 
 	b, _ := json.Marshal(types.PostArticlesRequestBody{
 		Title: "Lorem Ipsum",
@@ -307,11 +261,156 @@ func TestPostArticlesWithoutBody(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
-	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
 
 	assertEqual(t, err, nil)
 	assertEqual(t, respBody.Message, "Body is required.")
 }
+
+func TestPostArticlesWithMalformedAuthorizationHeader(t *testing.T) {
+	for _, header := range []string{"Bearer", "Bearer ", "Basic dGVzdDp0ZXN0", "Token abc"} {
+		req, _ := http.NewRequest("POST", "/api/articles", nil)
+		req.Header.Set("Authorization", header)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 401)
+		assertProblemHeader(t, rr)
+
+		respBody := &types.ErrorResponseBody{}
+		err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+		assertEqual(t, err, nil)
+		assertEqual(t, respBody.Message, "Authorization header must use the Bearer scheme.")
+	}
+}
+
+func TestPostArticlesWithWrongIssuer(t *testing.T) {
+	resetStore()
+
+	id, _ := createUserWithToken(t)
+	c := types.JWTClaims{
+		ID:       id,
+		Username: "test",
+		StandardClaims: jwt.StandardClaims{
+			Id:     createSession(t, id),
+			Issuer: "Someone Else",
+		},
+	}
+	ss, err := keySet.Sign(c)
+
+	assertEqual(t, err, nil)
+
+	rr := postArticleWithToken(ss)
+
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Invalid token.")
+}
+
+func TestGetArticleIsAuthor(t *testing.T) {
+	resetStore()
+
+	userID, ss := createUserWithToken(t)
+	articleID, err := store.CreateArticle(&models.Article{
+		UserID: userID,
+		Title:  "Title",
+		Body:   "Body",
+	})
+
+	assertEqual(t, err, nil)
+
+	tests := []struct {
+		header   string
+		isAuthor bool
+	}{
+		{"", false},
+		{"Bearer " + ss, true},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles/%d", articleID), nil)
+
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 200)
+
+		r := &types.GetArticleResponseBody{}
+		err := json.Unmarshal(rr.Body.Bytes(), r)
+
+		assertEqual(t, err, nil)
+		assertEqual(t, r.IsAuthor, test.isAuthor)
+	}
+}
+
+func TestGetArticleIsAuthorForOtherUser(t *testing.T) {
+	resetStore()
+
+	userID, _ := createUserWithToken(t)
+	articleID, err := store.CreateArticle(&models.Article{
+		UserID: userID,
+		Title:  "Title",
+		Body:   "Body",
+	})
+
+	assertEqual(t, err, nil)
+
+	otherID, err := store.CreateUser(&models.User{
+		Username: "other",
+		Email:    "other@test.com",
+		Password: "hash",
+	})
+
+	assertEqual(t, err, nil)
+
+	// The username claim is not authoritative; only the user ID decides
+	// authorship.
+	for _, username := range []string{"other", "test"} {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles/%d", articleID), nil)
+		req.Header.Set("Authorization", "Bearer "+newSessionToken(t, otherID, username))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 200)
+
+		r := &types.GetArticleResponseBody{}
+		err = json.Unmarshal(rr.Body.Bytes(), r)
+
+		assertEqual(t, err, nil)
+		assertEqual(t, r.IsAuthor, false)
+	}
+}
+
+func TestGetArticleWithInvalidToken(t *testing.T) {
+	resetStore()
+
+	userID, _ := createUserWithToken(t)
+	articleID, err := store.CreateArticle(&models.Article{
+		UserID: userID,
+		Title:  "Title",
+		Body:   "Body",
+	})
+
+	assertEqual(t, err, nil)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles/%d", articleID), nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,8 +14,7 @@ import (
 )
 
 func TestSuccessfulSignin(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -27,7 +27,7 @@ func TestSuccessfulSignin(t *testing.T) {
 		Password: string(hash),
 	}
 
-	_ = models.CreateUser(db, u)
+	store.CreateUser(u)
 
 	b, _ := json.Marshal(types.SigninRequestBody{
 		Username: "test",
@@ -53,7 +53,7 @@ func TestSigninWithoutBody(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -63,8 +63,7 @@ func TestSigninWithoutBody(t *testing.T) {
 }
 
 func TestSigninWithInvalidUsername(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	b, _ := json.Marshal(types.SigninRequestBody{
 		Username: "test",
@@ -75,19 +74,18 @@ func TestSigninWithInvalidUsername(t *testing.T) {
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
-	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
 
 	assertEqual(t, err, nil)
-	assertEqual(t, respBody.Message, "Username is invalid.")
+	assertEqual(t, respBody.Message, "Username or password is invalid.")
 }
 
 func TestSigninWithInvalidPassword(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -100,7 +98,7 @@ func TestSigninWithInvalidPassword(t *testing.T) {
 		Password: string(hash),
 	}
 
-	models.CreateUser(db, u)
+	store.CreateUser(u)
 
 	b, _ := json.Marshal(types.SigninRequestBody{
 		Username: "test",
@@ -111,25 +109,108 @@ func TestSigninWithInvalidPassword(t *testing.T) {
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
-	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Username or password is invalid.")
+}
+
+func TestSigninLockout(t *testing.T) {
+	resetStore()
+
+	password := "test"
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+
+	assertEqual(t, err, nil)
+
+	u := &models.User{
+		Username: "locked",
+		Email:    "locked@test.com",
+		Password: string(hash),
+	}
+
+	store.CreateUser(u)
+
+	for i := 0; i < 5; i++ {
+		b, _ := json.Marshal(types.SigninRequestBody{
+			Username: "locked",
+			Password: "invalid password",
+		})
+
+		req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 401)
+	}
+
+	b, _ := json.Marshal(types.SigninRequestBody{
+		Username: "locked",
+		Password: "test",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 429)
+	assertProblemHeader(t, rr)
+	assertEqual(t, rr.Header().Get("Retry-After"), "1")
 
 	respBody := &types.ErrorResponseBody{}
 	err = json.Unmarshal(rr.Body.Bytes(), respBody)
 
 	assertEqual(t, err, nil)
-	assertEqual(t, respBody.Message, "Password is invalid.")
+	assertEqual(t, respBody.Message, "Too many failed signin attempts. Try again later.")
+}
+
+func TestSigninThrottleTrustsOnlyConfiguredProxies(t *testing.T) {
+	resetStore()
+
+	trusting := options
+	trusting.TrustedProxies = []string{"192.0.2.1"}
+
+	for _, test := range []struct {
+		opts   Options
+		status int
+	}{
+		{options, 429},
+		{trusting, 401},
+	} {
+		r := New(store, test.opts)
+		var rr *httptest.ResponseRecorder
+
+		for i := 0; i <= 20; i++ {
+			b, _ := json.Marshal(types.SigninRequestBody{
+				Username: fmt.Sprintf("user%d", i),
+				Password: "password",
+			})
+
+			req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+		}
+
+		assertEqual(t, rr.Code, test.status)
+	}
 }
 
 func TestSuccessfulSignup(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	b, _ := json.Marshal(types.SignupRequestBody{
 		Username:        "test",
 		Email:           "test@test.com",
-		Password:        "test",
-		PasswordConfirm: "test",
+		Password:        "correct horse battery",
+		PasswordConfirm: "correct horse battery",
 	})
 
 	req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBuffer(b))
@@ -151,7 +232,7 @@ func TestSignupWithoutBody(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -168,7 +249,7 @@ func TestSignupWithEmptyBody(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -189,7 +270,7 @@ func TestSignupWithoutUsername(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -210,7 +291,7 @@ func TestSignupWithoutEmail(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -231,7 +312,7 @@ func TestSignupWithoutPassword(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -252,7 +333,7 @@ func TestSignupWithoutPasswordConfirm(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -274,7 +355,7 @@ func TestSignupWithMismatchPasswordAndPasswordConfirm(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err := json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -284,8 +365,7 @@ func TestSignupWithMismatchPasswordAndPasswordConfirm(t *testing.T) {
 }
 
 func TestSignUpWithRegisteredUsername(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -298,21 +378,21 @@ func TestSignUpWithRegisteredUsername(t *testing.T) {
 		Password: string(hash),
 	}
 
-	_ = models.CreateUser(db, u)
+	store.CreateUser(u)
 
 	b, _ := json.Marshal(types.SignupRequestBody{
 		Username:        "test",
 		Email:           "test2@test.com",
-		Password:        "test",
-		PasswordConfirm: "test",
+		Password:        "correct horse battery",
+		PasswordConfirm: "correct horse battery",
 	})
 
 	req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBuffer(b))
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
-	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertEqual(t, rr.Code, 409)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err = json.Unmarshal(rr.Body.Bytes(), respBody)
@@ -321,9 +401,26 @@ func TestSignUpWithRegisteredUsername(t *testing.T) {
 	assertEqual(t, respBody.Message, "Username is not available.")
 }
 
+func TestSignUpWithReservedUsername(t *testing.T) {
+	resetStore()
+
+	b, _ := json.Marshal(types.SignupRequestBody{
+		Username:        "deleted-1",
+		Email:           "test@test.com",
+		Password:        "correct horse battery",
+		PasswordConfirm: "correct horse battery",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBuffer(b))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 409)
+	assertEqual(t, problem(t, rr).Message, "Username is not available.")
+}
+
 func TestSignUpWithRegisteredEmail(t *testing.T) {
-	createUsersTable()
-	defer dropUsersTable()
+	resetStore()
 
 	password := "test"
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
@@ -336,21 +433,21 @@ func TestSignUpWithRegisteredEmail(t *testing.T) {
 		Password: string(hash),
 	}
 
-	_ = models.CreateUser(db, u)
+	store.CreateUser(u)
 
 	b, _ := json.Marshal(types.SignupRequestBody{
 		Username:        "test2",
 		Email:           "test@test.com",
-		Password:        "test",
-		PasswordConfirm: "test",
+		Password:        "correct horse battery",
+		PasswordConfirm: "correct horse battery",
 	})
 
 	req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBuffer(b))
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
-	assertEqual(t, rr.Code, 400)
-	assertJSONHeader(t, rr)
+	assertEqual(t, rr.Code, 409)
+	assertProblemHeader(t, rr)
 
 	respBody := &types.ErrorResponseBody{}
 	err = json.Unmarshal(rr.Body.Bytes(), respBody)
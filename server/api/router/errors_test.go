@@ -0,0 +1,161 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+type failingStore struct {
+	models.Store
+}
+
+func (s failingStore) WithContext(ctx context.Context) models.Store {
+	return s
+}
+
+func (s failingStore) GetArticle(id int) (*models.Article, error) {
+	return nil, errors.New(`pq: relation "articles" does not exist`)
+}
+
+func problem(t *testing.T, rr *httptest.ResponseRecorder) *types.ErrorResponseBody {
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Status, rr.Code)
+	assertEqual(t, respBody.Type, "about:blank")
+	assertEqual(t, respBody.Title, http.StatusText(rr.Code))
+	assertEqual(t, respBody.Detail, respBody.Message)
+
+	return respBody
+}
+
+func TestValidationErrorHasFieldDetails(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	req, _ := http.NewRequest("POST", "/api/articles", strings.NewReader(`{"body":"Body"}`))
+	req.Header.Set("Authorization", "Bearer "+ss)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 400)
+
+	respBody := problem(t, rr)
+
+	assertEqual(t, respBody.Code, "validation_failed")
+	assertEqual(t, respBody.Instance, "/api/articles")
+	assertEqual(t, len(respBody.Errors), 1)
+	assertEqual(t, respBody.Errors[0].Field, "title")
+	assertEqual(t, respBody.Errors[0].Message, "Title is required.")
+}
+
+func TestMalformedJSONIsBadRequest(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/api/signin", strings.NewReader(`{"username":`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 400)
+	assertEqual(t, problem(t, rr).Code, "bad_request")
+}
+
+func TestSignupConflict(t *testing.T) {
+	resetStore()
+
+	assertEqual(t, postSignup("newuser", "person@test.com", "correct horse battery").Code, 200)
+
+	rr := postSignup("newuser", "other@test.com", "correct horse battery")
+
+	assertEqual(t, rr.Code, 409)
+	assertEqual(t, problem(t, rr).Code, "conflict")
+}
+
+func TestUnknownRouteIsNotFound(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	req, _ := http.NewRequest("GET", "/api/unknown", nil)
+	req.Header.Set("Authorization", "Bearer "+ss)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+	assertEqual(t, problem(t, rr).Code, "not_found")
+}
+
+func TestStoreErrorsAreNotLeaked(t *testing.T) {
+	r := New(failingStore{models.NewMemoryStore()}, options)
+
+	req, _ := http.NewRequest("GET", "/api/articles/1", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 500)
+
+	respBody := problem(t, rr)
+
+	assertEqual(t, respBody.Code, "internal")
+	assertEqual(t, respBody.Message, "Internal server error.")
+}
+
+func TestSignupReportsAllInvalidFields(t *testing.T) {
+	resetStore()
+
+	rr := postSignup(strings.Repeat("a", 21), "not-an-email", "correct horse battery")
+
+	assertEqual(t, rr.Code, 400)
+
+	respBody := problem(t, rr)
+
+	assertEqual(t, respBody.Code, "validation_failed")
+	assertEqual(t, len(respBody.Errors), 2)
+	assertEqual(t, respBody.Errors[0].Message, "Username must be at most 20 characters.")
+	assertEqual(t, respBody.Errors[1].Message, "Email is invalid.")
+}
+
+func TestSignupTrimsUsername(t *testing.T) {
+	resetStore()
+
+	assertEqual(t, postSignup("  newuser ", "person@test.com", "correct horse battery").Code, 200)
+
+	_, err := store.GetUserByUsername("newuser")
+
+	assertEqual(t, err, nil)
+}
+
+func TestGetArticlesValidatesPage(t *testing.T) {
+	for _, page := range []string{"0", "-1", "abc", "1000001", "922337203685477582"} {
+		req, _ := http.NewRequest("GET", "/api/articles?page="+page, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 400)
+
+		respBody := problem(t, rr)
+
+		assertEqual(t, respBody.Code, "validation_failed")
+		assertEqual(t, len(respBody.Errors), 1)
+		assertEqual(t, respBody.Errors[0].Field, "page")
+	}
+}
+
+func TestGetArticlesLastPage(t *testing.T) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/articles?page=%d", models.MaxPage), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+}
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDisabledFeaturesAreNotRouted(t *testing.T) {
+	resetStore()
+
+	opts := options
+	opts.Features = Features{}
+	r := New(store, opts)
+
+	_, ss := createUserWithToken(t)
+
+	for _, endpoint := range []string{"/api/signup", "/api/auth/stub/start", "/api/me/2fa/setup"} {
+		req, _ := http.NewRequest("POST", endpoint, nil)
+		req.Header.Set("Authorization", "Bearer "+ss)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 404)
+	}
+}
+
+func TestSessionTokenLifetime(t *testing.T) {
+	resetStore()
+
+	opts := options
+	opts.TokenLifetimes.Session = time.Hour
+	router = conform(New(store, opts))
+
+	assertEqual(t, postSignup("newuser", "person@test.com", "correct horse battery").Code, 200)
+
+	claims := tokenClaims(t, signin("newuser", "correct horse battery"))
+
+	assertEqual(t, claims.ExpiresAt > time.Now().Unix(), true)
+	assertEqual(t, claims.ExpiresAt <= time.Now().Add(time.Hour).Unix(), true)
+
+	resetStore()
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/health"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func getReadyz(t *testing.T, checks ...health.Check) (int, *types.ReadinessResponseBody) {
+	opts := options
+	opts.Health = &health.Checker{Timeout: time.Second, Checks: checks}
+	r := New(store, opts)
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assertJSONHeader(t, rr)
+	assertEqual(t, strings.Contains(rr.Body.String(), "unreachable"), false)
+
+	respBody := &types.ReadinessResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	return rr.Code, respBody
+}
+
+func passing(name string) health.Check {
+	return health.Check{Name: name, Run: func(ctx context.Context) error { return nil }}
+}
+
+func failing(name string, optional bool) health.Check {
+	return health.Check{Name: name, Optional: optional, Run: func(ctx context.Context) error { return errors.New("unreachable") }}
+}
+
+func TestHealthz(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+}
+
+func TestReadyz(t *testing.T) {
+	code, body := getReadyz(t, passing("database"), passing("migrations"))
+
+	assertEqual(t, code, 200)
+	assertEqual(t, body.Status, "ok")
+	assertEqual(t, body.Checks["database"].Status, "ok")
+	assertEqual(t, body.Checks["migrations"].Status, "ok")
+}
+
+func TestReadyzWithFailingCheck(t *testing.T) {
+	code, body := getReadyz(t, failing("database", false), passing("migrations"))
+
+	assertEqual(t, code, 503)
+	assertEqual(t, body.Status, "unavailable")
+	assertEqual(t, body.Checks["database"].Status, "error")
+	assertEqual(t, body.Checks["migrations"].Status, "ok")
+}
+
+func TestReadyzWithFailingOptionalCheck(t *testing.T) {
+	code, body := getReadyz(t, passing("database"), failing("workers", true))
+
+	assertEqual(t, code, 200)
+	assertEqual(t, body.Status, "degraded")
+	assertEqual(t, body.Checks["workers"].Status, "error")
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func postArticleWithToken(ss string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(types.PostArticlesRequestBody{
+		Title: "Lorem Ipsum",
+		Body:  "Lorem ipsum dolor sit amet.",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/articles", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestGetJWKS(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.JWKSResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, len(respBody.Keys), 2)
+	assertEqual(t, respBody.Keys[0].Kid, "test-active")
+	assertEqual(t, respBody.Keys[0].Kty, "OKP")
+	assertEqual(t, respBody.Keys[0].Alg, "EdDSA")
+	assertEqual(t, respBody.Keys[1].Kid, "test-retiring")
+	assertEqual(t, respBody.Keys[1].Kty, "RSA")
+	assertEqual(t, respBody.Keys[1].Alg, "RS256")
+}
+
+func TestTokenSignedWithRetiringKey(t *testing.T) {
+	resetStore()
+
+	id, _ := createUserWithToken(t)
+
+	c := types.JWTClaims{
+		ID:       id,
+		Username: "test",
+		StandardClaims: jwt.StandardClaims{
+			Id:     createSession(t, id),
+			Issuer: "Composition",
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	token.Header["kid"] = retiringKey.ID
+	ss, err := token.SignedString(retiringKey.Private)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, postArticleWithToken(ss).Code, 201)
+}
+
+func TestTokenWithUnknownKid(t *testing.T) {
+	c := types.JWTClaims{
+		ID:       1,
+		Username: "test",
+		StandardClaims: jwt.StandardClaims{
+			Issuer: "Composition",
+		},
+	}
+	unknownKey := mustGenerateKey(keys.GenerateEd25519("unknown", keys.StateActive))
+	ss, err := mustNewKeySet(unknownKey).Sign(c)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, postArticleWithToken(ss).Code, 401)
+}
+
+func TestTokenWithMismatchedAlgorithm(t *testing.T) {
+	c := types.JWTClaims{
+		ID:       1,
+		Username: "test",
+		StandardClaims: jwt.StandardClaims{
+			Issuer: "Composition",
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	token.Header["kid"] = activeKey.ID
+	ss, err := token.SignedString([]byte(activeKey.Public.(ed25519.PublicKey)))
+
+	assertEqual(t, err, nil)
+	assertEqual(t, postArticleWithToken(ss).Code, 401)
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRequestLogging(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+
+	buf := &bytes.Buffer{}
+	opts := options
+	opts.Logger = zerolog.New(buf).Level(zerolog.DebugLevel)
+	r := New(store, opts)
+
+	req, _ := http.NewRequest("GET", "/api/me/sessions?page=1&token=secret", nil)
+	req.Header.Set("Authorization", "Bearer "+ss)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, rr.Header().Get("X-Request-ID"), "abc-123")
+
+	if strings.Contains(buf.String(), ss) || strings.Contains(buf.String(), "secret") {
+		t.Fatalf("log leaked a credential: %s", buf)
+	}
+
+	entry := map[string]interface{}{}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, entry["level"], "info")
+	assertEqual(t, entry["request_id"], "abc-123")
+	assertEqual(t, entry["route"], "/api/me/sessions")
+	assertEqual(t, entry["status"], float64(200))
+	assertEqual(t, entry["user_id"], float64(id))
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	for _, header := range []string{"", "not a valid id\n"} {
+		req, _ := http.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("X-Request-ID", header)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, len(rr.Header().Get("X-Request-ID")), 32)
+	}
+}
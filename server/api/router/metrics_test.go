@@ -0,0 +1,54 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T) string {
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	b, err := io.ReadAll(rr.Body)
+
+	assertEqual(t, err, nil)
+
+	return string(b)
+}
+
+func assertMetric(t *testing.T, body, line string) {
+	if !strings.Contains(body, line+"\n") {
+		t.Fatalf("metrics do not contain %q", line)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	resetStore()
+
+	assertEqual(t, postSignup("newuser", "person@test.com", "correct horse battery").Code, 200)
+	assertEqual(t, signin("newuser", "wrong").Code, 401)
+	assertEqual(t, signin("newuser", "correct horse battery").Code, 200)
+
+	_, ss := createUserWithToken(t)
+	assertEqual(t, postArticleWithToken(ss).Code, 201)
+
+	req, _ := http.NewRequest("GET", "/api/articles/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	body := scrape(t)
+
+	assertMetric(t, body, `composition_http_requests_total{method="POST",route="/api/signin",status="401"} 1`)
+	assertMetric(t, body, `composition_http_requests_total{method="POST",route="/api/signin",status="200"} 1`)
+	assertMetric(t, body, `composition_http_requests_total{method="GET",route="/api/articles/:id",status="404"} 1`)
+	assertMetric(t, body, `composition_http_request_duration_seconds_count{method="POST",route="/api/signup"} 1`)
+	assertMetric(t, body, `composition_signups_total{method="password"} 1`)
+	assertMetric(t, body, `composition_signins_total{method="password",result="failure"} 1`)
+	assertMetric(t, body, `composition_signins_total{method="password",result="success"} 1`)
+	assertMetric(t, body, `composition_articles_created_total 1`)
+}
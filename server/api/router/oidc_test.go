@@ -0,0 +1,359 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type stubIdentity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+type stubGrant struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+	identity    stubIdentity
+}
+
+type stubProvider struct {
+	*httptest.Server
+	keys *keys.KeySet
+
+	mu       sync.Mutex
+	grants   map[string]stubGrant
+	identity stubIdentity
+}
+
+func newStubProvider() *stubProvider {
+	k, err := keys.GenerateRSA("stub-key", keys.StateActive)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &stubProvider{
+		keys:   mustNewKeySet(k),
+		grants: map[string]stubGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *stubProvider) config() oidc.Config {
+	return oidc.Config{
+		Name:         "stub",
+		Issuer:       s.URL,
+		ClientID:     "composition",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/auth/stub/callback",
+	}
+}
+
+func (s *stubProvider) setIdentity(i stubIdentity) {
+	s.mu.Lock()
+	s.identity = i
+	s.mu.Unlock()
+}
+
+func (s *stubProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *stubProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != "composition" || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", 400)
+		return
+	}
+
+	code, _ := oidc.RandomString()
+
+	s.mu.Lock()
+	s.grants[code] = stubGrant{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+		identity:    s.identity,
+	}
+	s.mu.Unlock()
+
+	v := url.Values{}
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	http.Redirect(w, r, q.Get("redirect_uri")+"?"+v.Encode(), 302)
+}
+
+func (s *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+
+	if !ok || id != "composition" || secret != "secret" {
+		w.WriteHeader(401)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	r.ParseForm()
+	code := r.PostForm.Get("code")
+
+	s.mu.Lock()
+	g, ok := s.grants[code]
+	delete(s.grants, code)
+	s.mu.Unlock()
+
+	if !ok || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != g.challenge || r.PostForm.Get("redirect_uri") != g.redirectURI {
+		w.WriteHeader(400)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := s.keys.Sign(jwt.MapClaims{
+		"iss":                s.URL,
+		"sub":                g.identity.Subject,
+		"aud":                []string{"composition"},
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              g.nonce,
+		"email":              g.identity.Email,
+		"email_verified":     g.identity.EmailVerified,
+		"preferred_username": g.identity.PreferredUsername,
+	})
+
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (s *stubProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(s.keys.JWKS())
+}
+
+func oidcSignin(t *testing.T, i stubIdentity, tamper func(q url.Values)) *httptest.ResponseRecorder {
+	return oidcSigninAs(t, "", i, tamper)
+}
+
+// oidcSigninAs starts the sign in with the session token ss, if set.
+func oidcSigninAs(t *testing.T, ss string, i stubIdentity, tamper func(q url.Values)) *httptest.ResponseRecorder {
+	stub.setIdentity(i)
+
+	req, _ := http.NewRequest("GET", "/api/auth/stub/start", nil)
+
+	if ss != "" {
+		req.Header.Set("Authorization", "Bearer "+ss)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 302)
+
+	cookies := rr.Result().Cookies()
+
+	assertEqual(t, len(cookies), 1)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(rr.Header().Get("Location"))
+
+	assertEqual(t, err, nil)
+	assertEqual(t, resp.StatusCode, 302)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+
+	assertEqual(t, err, nil)
+	assertEqual(t, callback.Path, "/api/auth/stub/callback")
+
+	q := callback.Query()
+
+	if tamper != nil {
+		tamper(q)
+	}
+
+	req, _ = http.NewRequest("GET", callback.Path+"?"+q.Encode(), nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func tokenClaims(t *testing.T, rr *httptest.ResponseRecorder) *types.JWTClaims {
+	respBody := &types.SigninResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	claims := &types.JWTClaims{}
+	_, err = jwt.ParseWithClaims(respBody.Token, claims, keySet.Keyfunc)
+
+	assertEqual(t, err, nil)
+
+	return claims
+}
+
+func TestOIDCSigninCreatesUser(t *testing.T) {
+	resetStore()
+
+	i := stubIdentity{
+		Subject:           "1234",
+		Email:             "oidc@test.com",
+		EmailVerified:     true,
+		PreferredUsername: "OIDC.User",
+	}
+
+	rr := oidcSignin(t, i, nil)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	first := tokenClaims(t, rr)
+
+	assertEqual(t, first.Username, "oidcuser")
+
+	rr = oidcSignin(t, i, nil)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, tokenClaims(t, rr).ID, first.ID)
+}
+
+func TestOIDCSigninDoesNotLinkVerifiedEmail(t *testing.T) {
+	resetStore()
+
+	// Signup does not verify email, so the account may belong to someone
+	// other than the provider's user.
+	createUserWithToken(t)
+
+	rr := oidcSignin(t, stubIdentity{Subject: "1234", Email: "test@test.com", EmailVerified: true}, nil)
+
+	assertEqual(t, rr.Code, 409)
+	assertProblemHeader(t, rr)
+
+	_, err := store.GetUserByIdentity("stub", "1234")
+
+	assertEqual(t, err, models.ErrNotFound)
+}
+
+func TestOIDCSigninLinksFromSession(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+	i := stubIdentity{Subject: "1234", Email: "other@test.com", EmailVerified: true}
+
+	rr := oidcSigninAs(t, ss, i, nil)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, tokenClaims(t, rr).ID, id)
+
+	rr = oidcSignin(t, i, nil)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, tokenClaims(t, rr).ID, id)
+
+	otherID, err := store.CreateUser(&models.User{Username: "other", Email: "another@test.com", Password: "hash"})
+
+	assertEqual(t, err, nil)
+
+	rr = oidcSigninAs(t, newSessionToken(t, otherID, "other"), i, nil)
+
+	assertEqual(t, rr.Code, 409)
+}
+
+func TestOIDCSigninWithUnverifiedEmailCollision(t *testing.T) {
+	resetStore()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+
+	assertEqual(t, err, nil)
+
+	store.CreateUser(&models.User{
+		Username: "test",
+		Email:    "test@test.com",
+		Password: string(hash),
+	})
+
+	rr := oidcSignin(t, stubIdentity{Subject: "1234", Email: "test@test.com"}, nil)
+
+	assertEqual(t, rr.Code, 409)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Email is not available.")
+}
+
+func TestOIDCCallbackWithMismatchedState(t *testing.T) {
+	i := stubIdentity{Subject: "1234", Email: "oidc@test.com", EmailVerified: true}
+
+	rr := oidcSignin(t, i, func(q url.Values) {
+		q.Set("state", "forged")
+	})
+
+	assertEqual(t, rr.Code, 400)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Sign in state is missing or expired.")
+}
+
+func TestOIDCCallbackWithInvalidCode(t *testing.T) {
+	i := stubIdentity{Subject: "1234", Email: "oidc@test.com", EmailVerified: true}
+
+	rr := oidcSignin(t, i, func(q url.Values) {
+		q.Set("code", "invalid")
+	})
+
+	assertEqual(t, rr.Code, 502)
+}
+
+func TestOIDCStartWithUnknownProvider(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/auth/unknown/start", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+	assertProblemHeader(t, rr)
+}
@@ -0,0 +1,166 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/richardpanda/composition/server/api/openapi"
+)
+
+var (
+	spec       = mustLoadSpec()
+	specRoutes = mustNewSpecRouter(spec)
+
+	violationsMu sync.Mutex
+	violations   []string
+)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/zip", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.PlainBodyDecoder)
+}
+
+func mustLoadSpec() *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Spec)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func mustNewSpecRouter(doc *openapi3.T) routers.Router {
+	r, err := legacy.NewRouter(doc)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// conforming checks every response served during the router tests against
+// the OpenAPI document. Mismatches are reported when the tests finish.
+type conforming struct {
+	http.Handler
+}
+
+func conform(h http.Handler) http.Handler {
+	return conforming{h}
+}
+
+func (h conforming) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rr := httptest.NewRecorder()
+	h.Handler.ServeHTTP(rr, req)
+
+	for k, v := range rr.Header() {
+		w.Header()[k] = v
+	}
+
+	w.WriteHeader(rr.Code)
+	w.Write(rr.Body.Bytes())
+
+	if err := validateResponse(req, rr); err != nil {
+		violationsMu.Lock()
+		msg := strings.SplitN(err.Error(), "\n", 2)[0]
+		violations = append(violations, fmt.Sprintf("%s %s: %d: %s", req.Method, req.URL.Path, rr.Code, msg))
+		violationsMu.Unlock()
+	}
+}
+
+func validateResponse(req *http.Request, rr *httptest.ResponseRecorder) error {
+	v1 := req.Clone(context.Background())
+	v1.URL.Path = canonicalPath(req.URL.Path)
+
+	route, params, err := specRoutes.FindRoute(v1)
+
+	if err != nil {
+		// Unknown routes answer with a problem that no operation describes.
+		return nil
+	}
+
+	return openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: params,
+			Route:      route,
+		},
+		Status:  rr.Code,
+		Header:  rr.Header(),
+		Body:    io.NopCloser(bytes.NewReader(rr.Body.Bytes())),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	})
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, "response does not match openapi.json:", v)
+		code = 1
+	}
+
+	os.Exit(code)
+}
+
+// canonicalPath maps the unversioned /api alias onto /api/v1.
+func canonicalPath(path string) string {
+	if strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/api/v1/") {
+		return "/api/v1" + strings.TrimPrefix(path, "/api")
+	}
+	return path
+}
+
+var ginParam = regexp.MustCompile(`:([^/]+)`)
+
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	var registered, documented []string
+
+	for _, route := range New(store, options).Routes() {
+		if canonicalPath(route.Path) == route.Path {
+			registered = append(registered, route.Method+" "+ginParam.ReplaceAllString(route.Path, "{$1}"))
+		}
+	}
+
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+
+	assertEqual(t, strings.Join(documented, "\n"), strings.Join(registered, "\n"))
+}
+
+func TestGetOpenAPI(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+	assertEqual(t, bytes.Equal(rr.Body.Bytes(), openapi.Spec), true)
+}
+
+func TestGetDocs(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/docs", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, rr.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	assertEqual(t, strings.Contains(rr.Body.String(), `fetch("openapi.json")`), true)
+}
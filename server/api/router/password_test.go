@@ -0,0 +1,253 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func postSignup(username, email, password string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(types.SignupRequestBody{
+		Username:        username,
+		Email:           email,
+		Password:        password,
+		PasswordConfirm: password,
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBuffer(b))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func postPassword(ss string, body types.ChangePasswordRequestBody) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", "/api/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func signin(username, password string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(types.SigninRequestBody{
+		Username: username,
+		Password: password,
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestSignupWithWeakPasswords(t *testing.T) {
+	resetStore()
+
+	cases := []struct {
+		password string
+		message  string
+	}{
+		{"short", "Password must be at least 8 characters."},
+		{string(bytes.Repeat([]byte("a"), 73)), "Password must be at most 72 bytes."},
+		{"password1", "Password is too common."},
+		{"Composition", "Password is too common."},
+		{"newuser2020", "Password is too similar to your username or email."},
+		{"inbox-person", "Password is too similar to your username or email."},
+	}
+
+	for _, tc := range cases {
+		rr := postSignup("newuser", "person@test.com", tc.password)
+
+		assertEqual(t, rr.Code, 400)
+		assertProblemHeader(t, rr)
+
+		respBody := &types.ErrorResponseBody{}
+		err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+		assertEqual(t, err, nil)
+		assertEqual(t, respBody.Message, tc.message)
+	}
+}
+
+func TestSignupHashesWithConfiguredCost(t *testing.T) {
+	resetStore()
+
+	assertEqual(t, postSignup("newuser", "person@test.com", "correct horse battery").Code, 200)
+
+	u, err := store.GetUserByUsername("newuser")
+
+	assertEqual(t, err, nil)
+
+	cost, err := bcrypt.Cost([]byte(u.Password))
+
+	assertEqual(t, err, nil)
+	assertEqual(t, cost, passwordPolicy.Cost)
+}
+
+func TestSigninRehashesWhenCostChanges(t *testing.T) {
+	resetStore()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost+1)
+
+	assertEqual(t, err, nil)
+
+	store.CreateUser(&models.User{
+		Username: "test",
+		Email:    "test@test.com",
+		Password: string(hash),
+	})
+
+	assertEqual(t, signin("test", "test").Code, 200)
+
+	u, err := store.GetUserByUsername("test")
+
+	assertEqual(t, err, nil)
+
+	cost, err := bcrypt.Cost([]byte(u.Password))
+
+	assertEqual(t, err, nil)
+	assertEqual(t, cost, passwordPolicy.Cost)
+	assertEqual(t, signin("test", "test").Code, 200)
+}
+
+func TestChangePassword(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+	other := newSessionToken(t, id, "test")
+
+	rr := postPassword(ss, types.ChangePasswordRequestBody{
+		CurrentPassword:    "test",
+		NewPassword:        "correct horse battery",
+		NewPasswordConfirm: "correct horse battery",
+	})
+
+	assertEqual(t, rr.Code, 204)
+	assertEqual(t, signin("test", "test").Code, 401)
+	assertEqual(t, signin("test", "correct horse battery").Code, 200)
+
+	sessions := getSessions(t, ss).Sessions
+
+	assertEqual(t, len(sessions), 2)
+
+	req, _ := http.NewRequest("GET", "/api/me/sessions", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", other))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 401)
+}
+
+func TestChangePasswordWithInvalidCurrentPassword(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	rr := postPassword(ss, types.ChangePasswordRequestBody{
+		CurrentPassword:    "wrong",
+		NewPassword:        "correct horse battery",
+		NewPasswordConfirm: "correct horse battery",
+	})
+
+	assertEqual(t, rr.Code, 400)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Password is invalid.")
+}
+
+func TestChangePasswordWithWeakPassword(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	rr := postPassword(ss, types.ChangePasswordRequestBody{
+		CurrentPassword:    "test",
+		NewPassword:        "qwertyuiop",
+		NewPasswordConfirm: "qwertyuiop",
+	})
+
+	assertEqual(t, rr.Code, 400)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Password is too common.")
+}
+
+func TestChangePasswordWithMismatchedPasswords(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	rr := postPassword(ss, types.ChangePasswordRequestBody{
+		CurrentPassword:    "test",
+		NewPassword:        "correct horse battery",
+		NewPasswordConfirm: "correct horse battery staple",
+	})
+
+	assertEqual(t, rr.Code, 400)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "New passwords do not match.")
+}
+
+func TestChangePasswordWithoutCurrentPassword(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	rr := postPassword(ss, types.ChangePasswordRequestBody{
+		NewPassword:        "correct horse battery",
+		NewPasswordConfirm: "correct horse battery",
+	})
+
+	assertEqual(t, rr.Code, 400)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Current password is required.")
+}
+
+func TestSetPasswordWithoutPassword(t *testing.T) {
+	resetStore()
+
+	rr := oidcSignin(t, stubIdentity{Subject: "1234", Email: "oidc@test.com", EmailVerified: true, PreferredUsername: "oidcuser"}, nil)
+
+	assertEqual(t, rr.Code, 200)
+
+	respBody := &types.SigninResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, signin("oidcuser", "correct horse battery").Code, 401)
+
+	rr = postPassword(respBody.Token, types.ChangePasswordRequestBody{
+		NewPassword:        "correct horse battery",
+		NewPasswordConfirm: "correct horse battery",
+	})
+
+	assertEqual(t, rr.Code, 204)
+	assertEqual(t, signin("oidcuser", "correct horse battery").Code, 200)
+}
@@ -1,26 +1,160 @@
 package router
 
 import (
-	"database/sql"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/apierr"
 	"github.com/richardpanda/composition/server/api/controllers"
+	"github.com/richardpanda/composition/server/api/health"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/logging"
+	"github.com/richardpanda/composition/server/api/metrics"
 	"github.com/richardpanda/composition/server/api/middlewares"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/throttle"
+	"github.com/richardpanda/composition/server/api/tracing"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/rs/zerolog"
 )
 
-func New(db *sql.DB) *gin.Engine {
-	r := gin.Default()
+type Features struct {
+	Signup    bool
+	OIDC      bool
+	TwoFactor bool
+}
+
+// Deprecation announces the retirement of an API version. Either time may be
+// zero.
+type Deprecation struct {
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+type Options struct {
+	// TrustedProxies lists the addresses or CIDR ranges allowed to set
+	// X-Forwarded-For. When empty the client IP is the remote address.
+	TrustedProxies []string
+	Keys           *keys.KeySet
+	Providers      oidc.Providers
+	DeletionPolicy models.DeletionPolicy
+	PasswordPolicy *password.Policy
+	TokenLifetimes types.TokenLifetimes
+	Features       Features
+	Health         *health.Checker
+	Metrics        *metrics.Metrics
+	Logger         zerolog.Logger
+	// Deprecations is keyed by version name, e.g. "v1".
+	Deprecations map[string]Deprecation
+}
+
+type version struct {
+	name   string
+	routes func(g *gin.RouterGroup, opts Options)
+}
+
+// versions lists the API versions, oldest first. Each is served under
+// /api/<name>, and /api stays an alias of v1 for existing clients.
+var versions = []version{
+	{"v1", v1},
+}
+
+func New(store models.Store, opts Options) *gin.Engine {
+	r := gin.New()
+
+	if err := r.SetTrustedProxies(opts.TrustedProxies); err != nil {
+		panic(err)
+	}
+
+	r.Use(logging.Middleware(opts.Logger))
+	r.Use(logging.Recovery())
+	r.Use(tracing.Middleware())
+
+	m := opts.Metrics
+
+	if m == nil {
+		m = metrics.New()
+	}
 
-	r.Use(middlewares.DB(db))
+	r.Use(m.Middleware())
+	r.Use(apierr.Middleware())
+	r.Use(middlewares.Metrics(m))
+	r.Use(middlewares.Store(store))
+	r.Use(middlewares.Keys(opts.Keys))
+	r.Use(middlewares.OIDC(opts.Providers))
+	r.Use(middlewares.DeletionPolicy(opts.DeletionPolicy))
+	r.Use(middlewares.PasswordPolicy(opts.PasswordPolicy))
+	r.Use(middlewares.Throttle(throttle.NewSignin()))
+	r.Use(middlewares.TokenLifetimes(opts.TokenLifetimes))
+	r.Use(middlewares.Health(opts.Health))
 
-	r.GET("/api/articles/:id", controllers.GetArticle)
-	r.GET("/api/articles", controllers.GetArticles)
-	r.POST("/api/signin", controllers.PostSignin)
-	r.POST("/api/signup", controllers.PostSignup)
+	r.GET("/healthz", controllers.GetHealthz)
+	r.GET("/readyz", controllers.GetReadyz)
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
 
-	r.Use(middlewares.Authenticate())
+	for i, v := range versions {
+		var handlers []gin.HandlerFunc
 
-	r.POST("/api/articles", controllers.PostArticles)
+		if d, ok := opts.Deprecations[v.name]; ok {
+			successor := ""
+
+			if i+1 < len(versions) {
+				successor = "/api/" + versions[i+1].name
+			}
+
+			handlers = append(handlers, middlewares.Deprecation(d.DeprecatedAt, d.Sunset, successor))
+		}
+
+		v.routes(r.Group("/api/"+v.name, handlers...), opts)
+
+		if v.name == "v1" {
+			v.routes(r.Group("/api", handlers...), opts)
+		}
+	}
+
+	r.NoRoute(controllers.NoRoute)
 
 	return r
 }
+
+func v1(g *gin.RouterGroup, opts Options) {
+	g.GET("/docs", controllers.GetDocs)
+	g.GET("/openapi.json", controllers.GetOpenAPI)
+	g.GET("/articles/:id", middlewares.OptionalAuthenticate(), controllers.GetArticle)
+	g.GET("/articles", controllers.GetArticles)
+	g.POST("/signin", controllers.PostSignin)
+	g.POST("/signin/2fa", controllers.PostSigninTwoFactor)
+
+	if opts.Features.OIDC {
+		g.GET("/auth/:provider/callback", controllers.GetAuthCallback)
+		g.GET("/auth/:provider/start", middlewares.OptionalAuthenticate(), controllers.GetAuthStart)
+	}
+
+	if opts.Features.Signup {
+		g.POST("/signup", controllers.PostSignup)
+	}
+
+	authed := g.Group("", middlewares.Authenticate())
+
+	authed.POST("/articles", middlewares.RequireScope(types.ScopeArticlesWrite), controllers.PostArticles)
+
+	me := authed.Group("/me", middlewares.RequireScope(types.ScopeAccount))
+	me.DELETE("", controllers.DeleteMe)
+	me.GET("/export", controllers.GetExport)
+	me.POST("/password", controllers.PostPassword)
+
+	if opts.Features.TwoFactor {
+		me.POST("/2fa/confirm", controllers.PostTwoFactorConfirm)
+		me.POST("/2fa/setup", controllers.PostTwoFactorSetup)
+	}
+
+	me.DELETE("/api-keys/:id", controllers.DeleteAPIKey)
+	me.GET("/api-keys", controllers.GetAPIKeys)
+	me.POST("/api-keys", controllers.PostAPIKeys)
+	me.DELETE("/sessions/:id", controllers.DeleteSession)
+	me.GET("/sessions", controllers.GetSessions)
+	me.POST("/token", controllers.PostToken)
+}
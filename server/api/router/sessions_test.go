@@ -0,0 +1,208 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func getSessions(t *testing.T, ss string) *types.GetSessionsResponseBody {
+	req, _ := http.NewRequest("GET", "/api/me/sessions", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.GetSessionsResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	return respBody
+}
+
+func postToken(ss string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/v1/me/token", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestPostTokenKeepsSession(t *testing.T) {
+	resetStore()
+
+	opts := options
+	opts.TokenLifetimes.Session = time.Hour
+	router = conform(New(store, opts))
+
+	createUserWithToken(t)
+
+	rr := signin("test", "test")
+	old := tokenClaims(t, rr)
+
+	respBody := &types.SigninResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	rr = postToken(respBody.Token)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	claims := tokenClaims(t, rr)
+
+	assertEqual(t, claims.Id, old.Id)
+	assertEqual(t, claims.ID, old.ID)
+	assertEqual(t, claims.ExpiresAt >= old.ExpiresAt, true)
+
+	refreshed := &types.PostTokenResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), refreshed)
+
+	assertEqual(t, err, nil)
+
+	sessions := getSessions(t, refreshed.Token).Sessions
+
+	assertEqual(t, len(sessions), 2)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/me/sessions/%s", claims.Id), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", refreshed.Token))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 204)
+
+	for _, ss := range []string{respBody.Token, refreshed.Token} {
+		rr = postToken(ss)
+
+		assertEqual(t, rr.Code, 401)
+		assertEqual(t, problem(t, rr).Message, "Session has been revoked.")
+	}
+
+	resetStore()
+}
+
+func TestPostTokenWithAPIKey(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	key := createAPIKey(t, ss, types.APIKeyScopes...)
+
+	assertEqual(t, postToken(key.Key).Code, 403)
+}
+
+func TestSigninRecordsSession(t *testing.T) {
+	resetStore()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+
+	assertEqual(t, err, nil)
+
+	store.CreateUser(&models.User{
+		Username: "test",
+		Email:    "test@test.com",
+		Password: string(hash),
+	})
+
+	b, _ := json.Marshal(types.SigninRequestBody{
+		Username: "test",
+		Password: "test",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+	req.Header.Set("User-Agent", "Test Browser")
+	req.RemoteAddr = "192.0.2.10:1234"
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	respBody := &types.SigninResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	sessions := getSessions(t, respBody.Token).Sessions
+
+	assertEqual(t, len(sessions), 1)
+	assertEqual(t, sessions[0].UserAgent, "Test Browser")
+	assertEqual(t, sessions[0].IP, "192.0.2.10")
+	assertEqual(t, sessions[0].Current, true)
+}
+
+func TestDeleteSession(t *testing.T) {
+	resetStore()
+
+	id, phone := createUserWithToken(t)
+	laptop := newSessionToken(t, id, "test")
+
+	sessions := getSessions(t, laptop).Sessions
+
+	assertEqual(t, len(sessions), 2)
+
+	var phoneID string
+	for _, s := range sessions {
+		if !s.Current {
+			phoneID = s.ID
+		}
+	}
+
+	endpoint := fmt.Sprintf("/api/me/sessions/%s", phoneID)
+	req, _ := http.NewRequest("DELETE", endpoint, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", laptop))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 204)
+	assertEqual(t, len(getSessions(t, laptop).Sessions), 1)
+
+	rr = postArticleWithToken(phone)
+
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Session has been revoked.")
+}
+
+func TestDeleteSessionOfAnotherUser(t *testing.T) {
+	resetStore()
+
+	id, ss := createUserWithToken(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+
+	assertEqual(t, err, nil)
+
+	otherID, err := store.CreateUser(&models.User{
+		Username: "other",
+		Email:    "other@test.com",
+		Password: string(hash),
+	})
+
+	assertEqual(t, err, nil)
+	assertEqual(t, otherID != id, true)
+
+	endpoint := fmt.Sprintf("/api/me/sessions/%s", createSession(t, otherID))
+	req, _ := http.NewRequest("DELETE", endpoint, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+}
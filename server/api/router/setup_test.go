@@ -1,25 +1,71 @@
 package router
 
 import (
-	"database/sql"
-	"fmt"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"net/http/httptest"
-	"os"
 	"testing"
+	"time"
 
-	_ "github.com/lib/pq"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/keys"
 	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	user             = os.Getenv("DB_USER")
-	dbname           = os.Getenv("TEST_DB_NAME")
-	connectionString = fmt.Sprintf("user=%s dbname=%s sslmode=disable", user, dbname)
-	db, _            = sql.Open("postgres", connectionString)
-	router           = New(db)
+	activeKey      = mustGenerateKey(keys.GenerateEd25519("test-active", keys.StateActive))
+	retiringKey    = mustGenerateKey(keys.GenerateRSA("test-retiring", keys.StateRetiring))
+	keySet         = mustNewKeySet(activeKey, retiringKey)
+	stub           = newStubProvider()
+	providers      = oidc.Providers{"stub": oidc.NewProvider(stub.config())}
+	passwordPolicy = mustNewPolicy(password.NewPolicy(8, bcrypt.MinCost))
+	options        = Options{
+		Keys:           keySet,
+		Providers:      providers,
+		DeletionPolicy: models.DeletionPolicyDelete,
+		PasswordPolicy: passwordPolicy,
+		TokenLifetimes: types.TokenLifetimes{
+			Challenge:        5 * time.Minute,
+			OIDCState:        10 * time.Minute,
+			Reauthentication: 10 * time.Minute,
+		},
+		Features: Features{
+			Signup:    true,
+			OIDC:      true,
+			TwoFactor: true,
+		},
+	}
+	store  = models.NewMemoryStore()
+	router = conform(New(store, options))
 )
 
+func mustGenerateKey(k *keys.Key, err error) *keys.Key {
+	if err != nil {
+		log.Fatal(err)
+	}
+	return k
+}
+
+func mustNewPolicy(p *password.Policy, err error) *password.Policy {
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+func mustNewKeySet(ks ...*keys.Key) *keys.KeySet {
+	set, err := keys.NewKeySet(ks...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return set
+}
+
 func assertEqual(t *testing.T, actual, expected interface{}) {
 	if actual != expected {
 		t.Fatalf("\nActual:   %v\nExpected: %v", actual, expected)
@@ -30,30 +76,62 @@ func assertJSONHeader(t *testing.T, rr *httptest.ResponseRecorder) {
 	assertEqual(t, rr.Header().Get("Content-Type"), "application/json; charset=utf-8")
 }
 
-func createArticlesTable() {
-	_, err := models.CreateArticlesTable(db)
-	if err != nil {
-		log.Fatal(err)
-	}
+func assertProblemHeader(t *testing.T, rr *httptest.ResponseRecorder) {
+	assertEqual(t, rr.Header().Get("Content-Type"), "application/problem+json")
 }
 
-func dropArticlesTable() {
-	_, err := models.DropArticlesTable(db)
-	if err != nil {
-		log.Fatal(err)
+func createUserWithToken(t *testing.T) (int, string) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+
+	assertEqual(t, err, nil)
+
+	u := &models.User{
+		Username: "test",
+		Email:    "test@test.com",
+		Password: string(hash),
 	}
+
+	id, err := store.CreateUser(u)
+
+	assertEqual(t, err, nil)
+
+	return id, newSessionToken(t, id, u.Username)
 }
 
-func createUsersTable() {
-	_, err := models.CreateUsersTable(db)
-	if err != nil {
-		log.Fatal(err)
+func createSession(t *testing.T, userID int) string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+
+	assertEqual(t, err, nil)
+
+	s := &models.Session{
+		ID:     hex.EncodeToString(b),
+		UserID: userID,
 	}
+	err = store.CreateSession(s)
+
+	assertEqual(t, err, nil)
+
+	return s.ID
 }
 
-func dropUsersTable() {
-	_, err := models.DropUsersTable(db)
-	if err != nil {
-		log.Fatal(err)
+func newSessionToken(t *testing.T, id int, username string) string {
+	c := types.JWTClaims{
+		ID:       id,
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			Id:     createSession(t, id),
+			Issuer: "Composition",
+		},
 	}
+	ss, err := keySet.Sign(c)
+
+	assertEqual(t, err, nil)
+
+	return ss
+}
+
+func resetStore() {
+	store = models.NewMemoryStore()
+	router = conform(New(store, options))
 }
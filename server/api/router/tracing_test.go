@@ -0,0 +1,47 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/richardpanda/composition/server/api/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestsAreTraced(t *testing.T) {
+	resetStore()
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(tracing.Propagator())
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	req, _ := http.NewRequest("GET", "/api/articles/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 404)
+
+	spans := exporter.GetSpans()
+
+	assertEqual(t, len(spans), 1)
+	assertEqual(t, spans[0].Name, "GET /api/articles/:id")
+	assertEqual(t, spans[0].SpanContext.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assertEqual(t, spans[0].Parent.SpanID().String(), "00f067aa0ba902b7")
+	assertEqual(t, spans[0].Parent.IsRemote(), true)
+
+	found := false
+
+	for _, attr := range spans[0].Attributes {
+		if attr.Key == attribute.Key("http.response.status_code") && attr.Value.AsInt64() == 404 {
+			found = true
+		}
+	}
+
+	assertEqual(t, found, true)
+}
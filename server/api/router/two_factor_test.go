@@ -0,0 +1,283 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/totp"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func enableTwoFactor(t *testing.T, ss string) (string, []string) {
+	req, _ := http.NewRequest("POST", "/api/me/2fa/setup", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	setup := &types.TwoFactorSetupResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), setup)
+
+	assertEqual(t, err, nil)
+
+	code, err := totp.Code(setup.Secret, time.Now())
+
+	assertEqual(t, err, nil)
+
+	b, _ := json.Marshal(types.TwoFactorConfirmRequestBody{Code: code})
+	req, _ = http.NewRequest("POST", "/api/me/2fa/confirm", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	confirm := &types.TwoFactorConfirmResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), confirm)
+
+	assertEqual(t, err, nil)
+
+	return setup.Secret, confirm.RecoveryCodes
+}
+
+func signinChallenge(t *testing.T) string {
+	b, _ := json.Marshal(types.SigninRequestBody{
+		Username: "test",
+		Password: "test",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signin", bytes.NewBuffer(b))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.SigninChallengeResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+
+	return respBody.ChallengeToken
+}
+
+func TestTwoFactorSetup(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	req, _ := http.NewRequest("POST", "/api/me/2fa/setup", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.TwoFactorSetupResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.URI, totp.URI("Composition", "test", respBody.Secret))
+}
+
+func TestTwoFactorConfirmWithInvalidCode(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	req, _ := http.NewRequest("POST", "/api/me/2fa/setup", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+
+	b, _ := json.Marshal(types.TwoFactorConfirmRequestBody{Code: "abcdef"})
+	req, _ = http.NewRequest("POST", "/api/me/2fa/confirm", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ss))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 400)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Code is invalid.")
+}
+
+func TestSigninWithTwoFactor(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	secret, recoveryCodes := enableTwoFactor(t, ss)
+
+	assertEqual(t, len(recoveryCodes), 10)
+
+	challenge := signinChallenge(t)
+	// The current code was spent confirming, so use the next one.
+	code, err := totp.Code(secret, time.Now().Add(30*time.Second))
+
+	assertEqual(t, err, nil)
+
+	b, _ := json.Marshal(types.SigninTwoFactorRequestBody{
+		ChallengeToken: challenge,
+		Code:           code,
+	})
+
+	req, _ := http.NewRequest("POST", "/api/signin/2fa", bytes.NewBuffer(b))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertJSONHeader(t, rr)
+
+	respBody := &types.SigninResponseBody{}
+	err = json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Token != "", true)
+}
+
+func TestSigninWithRecoveryCode(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	_, recoveryCodes := enableTwoFactor(t, ss)
+
+	for _, expected := range []int{200, 401} {
+		b, _ := json.Marshal(types.SigninTwoFactorRequestBody{
+			ChallengeToken: signinChallenge(t),
+			Code:           recoveryCodes[0],
+		})
+
+		req, _ := http.NewRequest("POST", "/api/signin/2fa", bytes.NewBuffer(b))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, expected)
+	}
+}
+
+func TestTwoFactorCodesAndChallengesAreSingleUse(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	secret, recoveryCodes := enableTwoFactor(t, ss)
+
+	challenge, retried := signinChallenge(t), signinChallenge(t)
+	code, err := totp.Code(secret, time.Now().Add(30*time.Second))
+
+	assertEqual(t, err, nil)
+
+	tests := []struct {
+		challenge string
+		code      string
+		status    int
+		message   string
+	}{
+		{challenge, code, 200, ""},
+		{retried, code, 401, "Code is invalid."},
+		{challenge, recoveryCodes[0], 401, "Challenge token is invalid or expired."},
+		{retried, recoveryCodes[0], 200, ""},
+		{retried, recoveryCodes[1], 401, "Challenge token is invalid or expired."},
+		{signinChallenge(t), recoveryCodes[1], 200, ""},
+	}
+
+	for _, test := range tests {
+		b, _ := json.Marshal(types.SigninTwoFactorRequestBody{
+			ChallengeToken: test.challenge,
+			Code:           test.code,
+		})
+
+		req, _ := http.NewRequest("POST", "/api/signin/2fa", bytes.NewBuffer(b))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, test.status)
+
+		if test.status != 200 {
+			respBody := &types.ErrorResponseBody{}
+			err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+			assertEqual(t, err, nil)
+			assertEqual(t, respBody.Message, test.message)
+		}
+	}
+}
+
+func TestTwoFactorFailuresSurvivePasswordSignin(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	enableTwoFactor(t, ss)
+
+	r := New(store, options)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(b))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 5; i++ {
+		rr := post("/api/signin", types.SigninRequestBody{Username: "test", Password: "test"})
+
+		assertEqual(t, rr.Code, 200)
+
+		respBody := &types.SigninChallengeResponseBody{}
+		err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+		assertEqual(t, err, nil)
+
+		rr = post("/api/signin/2fa", types.SigninTwoFactorRequestBody{
+			ChallengeToken: respBody.ChallengeToken,
+			Code:           "abcdef",
+		})
+
+		assertEqual(t, rr.Code, 401)
+	}
+
+	rr := post("/api/signin", types.SigninRequestBody{Username: "test", Password: "test"})
+
+	assertEqual(t, rr.Code, 429)
+}
+
+func TestChallengeTokenIsNotASessionToken(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+	enableTwoFactor(t, ss)
+
+	challenge := signinChallenge(t)
+
+	b, _ := json.Marshal(types.PostArticlesRequestBody{
+		Title: "Lorem Ipsum",
+		Body:  "Lorem ipsum",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/articles", bytes.NewBuffer(b))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", challenge))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 401)
+	assertProblemHeader(t, rr)
+
+	respBody := &types.ErrorResponseBody{}
+	err := json.Unmarshal(rr.Body.Bytes(), respBody)
+
+	assertEqual(t, err, nil)
+	assertEqual(t, respBody.Message, "Invalid token.")
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richardpanda/composition/server/api/controllers"
+)
+
+func TestAPIAliasServesV1(t *testing.T) {
+	var alias, v1 []string
+
+	for _, route := range New(store, options).Routes() {
+		switch {
+		case strings.HasPrefix(route.Path, "/api/v1/"):
+			v1 = append(v1, route.Method+" "+route.Path)
+		case strings.HasPrefix(route.Path, "/api/"):
+			alias = append(alias, route.Method+" "+canonicalPath(route.Path))
+		}
+	}
+
+	sort.Strings(alias)
+	sort.Strings(v1)
+
+	assertEqual(t, len(v1) > 0, true)
+	assertEqual(t, strings.Join(alias, "\n"), strings.Join(v1, "\n"))
+}
+
+func TestV1Routes(t *testing.T) {
+	resetStore()
+
+	_, ss := createUserWithToken(t)
+
+	for _, endpoint := range []string{"/api/v1/articles", "/api/v1/me/sessions"} {
+		req, _ := http.NewRequest("GET", endpoint, nil)
+		req.Header.Set("Authorization", "Bearer "+ss)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Code, 200)
+		assertEqual(t, rr.Header().Get("Deprecation"), "")
+	}
+}
+
+func TestDeprecatedVersionHeaders(t *testing.T) {
+	resetStore()
+
+	versions = append(versions, version{"v2", func(g *gin.RouterGroup, opts Options) {
+		g.GET("/articles", controllers.GetArticles)
+	}})
+	defer func() { versions = versions[:1] }()
+
+	deprecatedAt := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	opts := options
+	opts.Deprecations = map[string]Deprecation{"v1": {DeprecatedAt: deprecatedAt, Sunset: sunset}}
+	r := New(store, opts)
+
+	for _, endpoint := range []string{"/api/v1/articles", "/api/articles", "/api/v1/articles/1"} {
+		req, _ := http.NewRequest("GET", endpoint, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assertEqual(t, rr.Header().Get("Deprecation"), "@1798761600")
+		assertEqual(t, rr.Header().Get("Sunset"), "Thu, 01 Jul 2027 00:00:00 GMT")
+		assertEqual(t, rr.Header().Get("Link"), `</api/v2>; rel="successor-version"`)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v2/articles", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 200)
+	assertEqual(t, rr.Header().Get("Deprecation"), "")
+	assertEqual(t, rr.Header().Get("Sunset"), "")
+}
+
+func TestOIDCStateCookieCoversEveryVersion(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/api/v1/auth/stub/start", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assertEqual(t, rr.Code, 302)
+
+	cookies := rr.Result().Cookies()
+
+	assertEqual(t, len(cookies), 1)
+	assertEqual(t, cookies[0].Name, "oidc_state_stub")
+	assertEqual(t, cookies[0].Path, "/api")
+}
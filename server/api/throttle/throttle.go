@@ -0,0 +1,134 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+type Limiter struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	window    time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func NewLimiter(threshold int, base, max, window time.Duration) *Limiter {
+	return &Limiter{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		window:    window,
+		now:       time.Now,
+		entries:   map[string]*entry{},
+	}
+}
+
+func (l *Limiter) Blocked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+
+	if !ok {
+		return 0
+	}
+
+	if d := e.blockedUntil.Sub(l.now()); d > 0 {
+		return d
+	}
+
+	return 0
+}
+
+func (l *Limiter) Failure(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	e, ok := l.entries[key]
+
+	if !ok || now.Sub(e.lastFailure) > l.window {
+		e = &entry{}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	e.lastFailure = now
+
+	if e.failures < l.threshold {
+		return 0
+	}
+
+	d := l.max
+	if n := uint(e.failures - l.threshold); n < 32 && l.base<<n < l.max {
+		d = l.base << n
+	}
+
+	e.blockedUntil = now.Add(d)
+	return d
+}
+
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+
+	for key, e := range l.entries {
+		if now.Sub(e.lastFailure) > l.window && now.After(e.blockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+type Signin struct {
+	IP      *Limiter
+	Account *Limiter
+}
+
+func NewSignin() *Signin {
+	return &Signin{
+		IP:      NewLimiter(20, time.Second, 15*time.Minute, time.Hour),
+		Account: NewLimiter(5, time.Second, 15*time.Minute, time.Hour),
+	}
+}
+
+func (s *Signin) Blocked(ip, account string) time.Duration {
+	d := s.IP.Blocked(ip)
+
+	if a := s.Account.Blocked(account); a > d {
+		d = a
+	}
+
+	return d
+}
+
+func (s *Signin) Failure(ip, account string) {
+	s.IP.Failure(ip)
+	s.Account.Failure(account)
+}
+
+func (s *Signin) Success(account string) {
+	s.Account.Reset(account)
+}
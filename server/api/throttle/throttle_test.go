@@ -0,0 +1,53 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterBacksOffExponentially(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter(3, time.Second, 10*time.Second, time.Hour)
+	l.now = func() time.Time { return now }
+
+	expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+
+	for i, e := range expected {
+		if d := l.Failure("test"); d != e {
+			t.Fatalf("failure %d: got %v, expected %v", i+1, d, e)
+		}
+	}
+
+	if d := l.Blocked("test"); d != 10*time.Second {
+		t.Fatalf("got %v, expected %v", d, 10*time.Second)
+	}
+
+	now = now.Add(10 * time.Second)
+
+	if d := l.Blocked("test"); d != 0 {
+		t.Fatalf("got %v, expected 0", d)
+	}
+}
+
+func TestLimiterForgetsOldFailures(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter(2, time.Second, time.Minute, time.Hour)
+	l.now = func() time.Time { return now }
+
+	l.Failure("test")
+	now = now.Add(2 * time.Hour)
+
+	if d := l.Failure("test"); d != 0 {
+		t.Fatalf("got %v, expected 0", d)
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	l := NewLimiter(1, time.Minute, time.Hour, time.Hour)
+	l.Failure("test")
+	l.Reset("test")
+
+	if d := l.Blocked("test"); d != 0 {
+		t.Fatalf("got %v, expected 0", d)
+	}
+}
@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	digits = 6
+	period = 30
+	skew   = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(digits))
+	v.Set("period", fmt.Sprint(period))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
+
+func Code(secret string, t time.Time) (string, error) {
+	return codeAt(secret, uint64(t.Unix()/period))
+}
+
+// Validate reports the time step that code matches, allowing one step of
+// clock skew either way. Steps at or before last are rejected so that an
+// accepted code cannot be replayed.
+func Validate(code, secret string, t time.Time, last int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+
+	if len(code) != digits {
+		return 0, false
+	}
+
+	step := t.Unix() / period
+
+	for i := -skew; i <= skew; i++ {
+		if step+int64(i) <= last {
+			continue
+		}
+
+		expected, err := codeAt(secret, uint64(step+int64(i)))
+
+		if err != nil {
+			return 0, false
+		}
+
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step + int64(i), true
+		}
+	}
+
+	return 0, false
+}
+
+func codeAt(secret string, counter uint64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000), nil
+}
@@ -1,12 +1,21 @@
 package types
 
 import (
-	"os"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
 type ArticlePreview struct {
 	Username  string    `json:"username"`
 	Title     string    `json:"title"`
@@ -14,23 +23,115 @@ type ArticlePreview struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ChangePasswordRequestBody needs CurrentPassword unless the account has none.
+type ChangePasswordRequestBody struct {
+	CurrentPassword    string `json:"current_password"`
+	NewPassword        string `json:"new_password" binding:"required"`
+	NewPasswordConfirm string `json:"new_password_confirm" binding:"required,eqfield=NewPassword"`
+}
+
+const ChallengeAudience = "Composition 2FA"
+
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// DeleteMeRequestBody needs Password unless the account has none.
+type DeleteMeRequestBody struct {
+	Password string `json:"password"`
+}
+
 type ErrorResponseBody struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
+type Export struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	Profile    ExportProfile    `json:"profile"`
+	Articles   []ExportArticle  `json:"articles"`
+	Identities []ExportIdentity `json:"identities"`
+	Sessions   []Session        `json:"sessions"`
+	APIKeys    []APIKey         `json:"api_keys"`
+}
+
+type ExportArticle struct {
+	ID        int       `json:"article_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportProfile struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type GetAPIKeysResponseBody struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
+
 type GetArticleResponseBody struct {
 	ID        int       `json:"article_id"`
 	Title     string    `json:"title"`
 	Body      string    `json:"body"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
+	IsAuthor  bool      `json:"is_author"`
+}
+
+// GetArticlesQuery is the query string of GET /articles. The page limit
+// matches models.MaxPage.
+type GetArticlesQuery struct {
+	Page int `form:"page" binding:"min=1,max=1000000"`
 }
 
 type GetArticlesResponseBody struct {
 	ArticlePreviews []ArticlePreview `json:"article_previews"`
 }
 
-var JWTSecret = []byte(os.Getenv("JWT_SECRET"))
+type GetSessionsResponseBody struct {
+	Sessions []Session `json:"sessions"`
+}
+
+type HealthResponseBody struct {
+	Status string `json:"status"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSResponseBody struct {
+	Keys []JWK `json:"keys"`
+}
 
 type JWTClaims struct {
 	ID       int    `json:"id"`
@@ -38,9 +139,33 @@ type JWTClaims struct {
 	jwt.StandardClaims
 }
 
+const OIDCStateAudience = "Composition OIDC"
+
+// OIDCStateClaims carries a sign in across the provider redirect. UserID is
+// set when a signed in user started it to connect the provider.
+type OIDCStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	UserID   int    `json:"user_id,omitempty"`
+	jwt.StandardClaims
+}
+
+type PostAPIKeysRequestBody struct {
+	Name      string     `json:"name" binding:"required,max=50" normalize:"trim,nfc"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type PostAPIKeysResponseBody struct {
+	APIKey
+	Key string `json:"key"`
+}
+
 type PostArticlesRequestBody struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
+	Title string `json:"title" binding:"required,max=100" normalize:"trim,nfc"`
+	Body  string `json:"body" binding:"required" normalize:"trim,nfc"`
 }
 
 type PostArticlesResponseBody struct {
@@ -49,22 +174,81 @@ type PostArticlesResponseBody struct {
 	Body      string `json:"body"`
 }
 
+const (
+	ScopeAccount       = "account"
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+)
+
+var APIKeyScopes = []string{ScopeArticlesRead, ScopeArticlesWrite}
+
+type PostTokenResponseBody struct {
+	Token string `json:"token"`
+}
+
+type ReadinessResponseBody struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type SigninChallengeResponseBody struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
 type SigninRequestBody struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" binding:"required" normalize:"trim,nfc"`
+	Password string `json:"password" binding:"required"`
 }
 
 type SigninResponseBody struct {
 	Token string `json:"token"`
 }
 
+type SigninTwoFactorRequestBody struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required" normalize:"trim"`
+}
+
 type SignupRequestBody struct {
-	Username        string `json:"username"`
-	Email           string `json:"email"`
-	Password        string `json:"password"`
-	PasswordConfirm string `json:"password_confirm"`
+	Username        string `json:"username" binding:"required,max=20" normalize:"trim,nfc"`
+	Email           string `json:"email" binding:"required,max=50,email" normalize:"trim,nfc"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required,eqfield=Password"`
 }
 
 type SignupResponseBody struct {
 	Token string `json:"token"`
 }
+
+const TokenIssuer = "Composition"
+
+type TokenLifetimes struct {
+	Session   time.Duration
+	Challenge time.Duration
+	OIDCState time.Duration
+	// Reauthentication is how recently an account without a password must
+	// have signed in to confirm changes that otherwise ask for the password.
+	Reauthentication time.Duration
+}
+
+type TwoFactorConfirmRequestBody struct {
+	Code string `json:"code" binding:"required" normalize:"trim"`
+}
+
+type TwoFactorConfirmResponseBody struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorSetupResponseBody struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
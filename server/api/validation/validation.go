@@ -0,0 +1,174 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/richardpanda/composition/server/api/apierr"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/text/unicode/norm"
+)
+
+func Bind(c *gin.Context, v interface{}) *apierr.Error {
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		return apierr.BadRequest("Request body must be valid JSON.")
+	}
+
+	return Validate(v)
+}
+
+// BindQuery sets the form-tagged fields of v from the query string and
+// validates v. Parameters that are absent keep the values already in v.
+func BindQuery(c *gin.Context, v interface{}) *apierr.Error {
+	err := c.ShouldBindQuery(v)
+
+	if numErr := (*strconv.NumError)(nil); errors.As(err, &numErr) {
+		if name := queryName(v, c.Request.URL.Query(), numErr.Num); name != "" {
+			return apierr.Field(name, label(name)+" is invalid.")
+		}
+	}
+
+	if _, ok := err.(validator.ValidationErrors); err != nil && !ok {
+		return apierr.BadRequest("Query string is invalid.")
+	}
+
+	return Validate(v)
+}
+
+// queryName returns the parameter of v's fields that holds value.
+func queryName(v interface{}, q url.Values, value string) string {
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("form"); name != "" && q.Get(name) == value {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func Validate(v interface{}) *apierr.Error {
+	Normalize(v)
+
+	err := binding.Validator.ValidateStruct(v)
+
+	if err == nil {
+		return nil
+	}
+
+	errs, ok := err.(validator.ValidationErrors)
+
+	if !ok {
+		return apierr.Internal(err)
+	}
+
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	fields := make([]types.FieldError, 0, len(errs))
+
+	for _, fe := range errs {
+		name := jsonName(t, fe.StructField())
+		fields = append(fields, types.FieldError{Field: name, Message: message(t, name, fe)})
+	}
+
+	return apierr.Validation(fields[0].Message, fields...)
+}
+
+// Required reports a missing field the same way Validate does.
+func Required(field string) *apierr.Error {
+	return apierr.Field(field, label(field)+" is required.")
+}
+
+func Normalize(v interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Field(i)
+		tag := rv.Type().Field(i).Tag.Get("normalize")
+
+		if f.Kind() != reflect.String || tag == "" || !f.CanSet() {
+			continue
+		}
+
+		s := f.String()
+
+		for _, op := range strings.Split(tag, ",") {
+			switch op {
+			case "trim":
+				s = strings.TrimSpace(s)
+			case "nfc":
+				s = norm.NFC.String(s)
+			}
+		}
+
+		f.SetString(s)
+	}
+}
+
+func message(t reflect.Type, name string, fe validator.FieldError) string {
+	l := label(name)
+
+	switch fe.Tag() {
+	case "required":
+		if fe.Kind() == reflect.Slice {
+			return l + " are required."
+		}
+		return l + " is required."
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return l + " are required."
+		}
+		if fe.Kind() == reflect.Int {
+			return fmt.Sprintf("%s must be at least %s.", l, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s characters.", l, fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Int {
+			return fmt.Sprintf("%s must be at most %s.", l, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters.", l, fe.Param())
+	case "email":
+		return l + " is invalid."
+	case "eqfield":
+		return label(jsonName(t, fe.Param())) + "s do not match."
+	}
+
+	return l + " is invalid."
+}
+
+func jsonName(t reflect.Type, field string) string {
+	f, ok := t.FieldByName(field)
+
+	if !ok {
+		return field
+	}
+
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+	if name == "" {
+		name = f.Tag.Get("form")
+	}
+
+	if name == "" || name == "-" {
+		return field
+	}
+
+	return name
+}
+
+func label(name string) string {
+	s := strings.ReplaceAll(name, "_", " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
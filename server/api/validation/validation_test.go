@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func TestValidateReportsAllFields(t *testing.T) {
+	body := &types.SignupRequestBody{Email: "invalid", Password: "a", PasswordConfirm: "b"}
+	err := Validate(body)
+
+	if err == nil {
+		t.Fatal("got nil, want error")
+	}
+
+	want := map[string]string{
+		"username":         "Username is required.",
+		"email":            "Email is invalid.",
+		"password_confirm": "Passwords do not match.",
+	}
+
+	if len(err.Fields) != len(want) {
+		t.Fatalf("got %+v", err.Fields)
+	}
+
+	for _, f := range err.Fields {
+		if want[f.Field] != f.Message {
+			t.Fatalf("got %q for %s, want %q", f.Message, f.Field, want[f.Field])
+		}
+	}
+
+	if err.Message != err.Fields[0].Message {
+		t.Fatalf("got %q", err.Message)
+	}
+}
+
+func TestValidateCountsCharacters(t *testing.T) {
+	body := &types.PostArticlesRequestBody{Title: strings.Repeat("é", 100), Body: "Body"}
+
+	if err := Validate(body); err != nil {
+		t.Fatalf("got %+v", err.Fields)
+	}
+
+	body.Title += "e"
+	err := Validate(body)
+
+	if err == nil || err.Fields[0].Message != "Title must be at most 100 characters." {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	body := &types.SignupRequestBody{Username: "  José ", Password: " secret "}
+	Normalize(body)
+
+	if body.Username != "José" {
+		t.Fatalf("got %q", body.Username)
+	}
+
+	if body.Password != " secret " {
+		t.Fatalf("got %q, passwords should not be normalized", body.Password)
+	}
+}
+
+func TestValidateReportsNumberBounds(t *testing.T) {
+	err := Validate(&types.GetArticlesQuery{Page: 0})
+
+	if err == nil || err.Fields[0].Field != "page" || err.Fields[0].Message != "Page must be at least 1." {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// DeleteAccount deletes or anonymizes the account, per the server's policy,
+// and forgets the token.
+func (c *Client) DeleteAccount(ctx context.Context, body types.DeleteMeRequestBody) error {
+	if err := c.do(ctx, request{method: "DELETE", path: "/api/v1/me", body: body, auth: true}, nil); err != nil {
+		return err
+	}
+
+	c.SetToken("")
+	return nil
+}
+
+// Export returns the account's data as a zip archive.
+func (c *Client) Export(ctx context.Context) ([]byte, error) {
+	var archive []byte
+
+	if err := c.do(ctx, request{method: "GET", path: "/api/v1/me/export", auth: true}, &archive); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func (c *Client) CreateAPIKey(ctx context.Context, body types.PostAPIKeysRequestBody) (*types.PostAPIKeysResponseBody, error) {
+	resp := &types.PostAPIKeysResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/me/api-keys", body: body, auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) DeleteAPIKey(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: "DELETE", path: "/api/v1/me/api-keys/" + strconv.Itoa(id), auth: true}, nil)
+}
+
+func (c *Client) GetAPIKeys(ctx context.Context) ([]types.APIKey, error) {
+	resp := &types.GetAPIKeysResponseBody{}
+
+	if err := c.do(ctx, request{method: "GET", path: "/api/v1/me/api-keys", auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp.APIKeys, nil
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func (c *Client) CreateArticle(ctx context.Context, body types.PostArticlesRequestBody) (*types.PostArticlesResponseBody, error) {
+	resp := &types.PostArticlesResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/articles", body: body, auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetArticle sends the token when there is one, so IsAuthor is reported.
+func (c *Client) GetArticle(ctx context.Context, id int) (*types.GetArticleResponseBody, error) {
+	resp := &types.GetArticleResponseBody{}
+	r := request{method: "GET", path: "/api/v1/articles/" + strconv.Itoa(id), auth: c.Token() != ""}
+
+	if err := c.do(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetArticlePreviews returns one page of previews, newest first. Pages start
+// at 1.
+func (c *Client) GetArticlePreviews(ctx context.Context, page int) ([]types.ArticlePreview, error) {
+	resp := &types.GetArticlesResponseBody{}
+	r := request{method: "GET", path: "/api/v1/articles", query: url.Values{"page": {strconv.Itoa(page)}}}
+
+	if err := c.do(ctx, r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp.ArticlePreviews, nil
+}
+
+// ArticlePreviews iterates over every article preview, newest first,
+// fetching pages as needed:
+//
+//	it := c.ArticlePreviews(ctx)
+//	for it.Next() {
+//		p := it.Preview()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+func (c *Client) ArticlePreviews(ctx context.Context) *ArticlePreviewIterator {
+	return &ArticlePreviewIterator{c: c, ctx: ctx}
+}
+
+type ArticlePreviewIterator struct {
+	c       *Client
+	ctx     context.Context
+	page    int
+	buf     []types.ArticlePreview
+	current types.ArticlePreview
+	done    bool
+	err     error
+}
+
+func (it *ArticlePreviewIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if len(it.buf) == 0 {
+		it.page++
+		it.buf, it.err = it.c.GetArticlePreviews(it.ctx, it.page)
+
+		if it.err != nil || len(it.buf) == 0 {
+			it.done = true
+			return false
+		}
+	}
+
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+func (it *ArticlePreviewIterator) Preview() types.ArticlePreview {
+	return it.current
+}
+
+func (it *ArticlePreviewIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// Signin signs in and renews the session token before it expires. If the
+// account has two-factor authentication enabled it returns a *ChallengeError.
+func (c *Client) Signin(ctx context.Context, body types.SigninRequestBody) error {
+	var resp struct {
+		types.SigninResponseBody
+		types.SigninChallengeResponseBody
+	}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/signin", body: body}, &resp); err != nil {
+		return err
+	}
+
+	if resp.ChallengeToken != "" {
+		return &ChallengeError{ChallengeToken: resp.ChallengeToken}
+	}
+
+	c.SetToken(resp.Token)
+	return nil
+}
+
+// SigninTwoFactor completes a sign in that returned a *ChallengeError.
+func (c *Client) SigninTwoFactor(ctx context.Context, body types.SigninTwoFactorRequestBody) error {
+	resp := &types.SigninResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/signin/2fa", body: body}, resp); err != nil {
+		return err
+	}
+
+	c.SetToken(resp.Token)
+	return nil
+}
+
+func (c *Client) Signup(ctx context.Context, body types.SignupRequestBody) error {
+	resp := &types.SignupResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/signup", body: body}, resp); err != nil {
+		return err
+	}
+
+	c.SetToken(resp.Token)
+	return nil
+}
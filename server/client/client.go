@@ -0,0 +1,309 @@
+// Package client is a Go client for the Composition API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+const (
+	defaultMaxRetries    = 3
+	defaultRefreshBefore = time.Minute
+	maxBackoff           = 5 * time.Second
+)
+
+type Options struct {
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Token is a session token or API key to start with. Session tokens
+	// that expire are renewed like those obtained by signing in.
+	Token string
+	// MaxRetries bounds retries of GET and DELETE requests that fail with a
+	// 5xx status or a transport error. Zero means the default of 3; negative disables retries.
+	MaxRetries int
+	// Backoff returns the delay before the given retry, counting from zero.
+	// It defaults to exponential backoff with jitter. A Retry-After header
+	// from the server takes precedence.
+	Backoff func(retry int) time.Duration
+	// RefreshBefore is how long before expiry a session token is renewed.
+	// It defaults to a minute.
+	RefreshBefore time.Duration
+}
+
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	maxRetries    int
+	backoff       func(int) time.Duration
+	refreshBefore time.Duration
+
+	mu    sync.Mutex
+	token string
+
+	refreshMu sync.Mutex
+}
+
+func New(baseURL string, opts Options) *Client {
+	c := &Client{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient:    opts.HTTPClient,
+		maxRetries:    opts.MaxRetries,
+		backoff:       opts.Backoff,
+		refreshBefore: opts.RefreshBefore,
+		token:         opts.Token,
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+
+	if c.backoff == nil {
+		c.backoff = exponentialBackoff
+	}
+
+	if c.refreshBefore == 0 {
+		c.refreshBefore = defaultRefreshBefore
+	}
+
+	return c
+}
+
+// Token returns the token sent with authenticated requests.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken replaces the token.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// currentToken returns a token that is not about to expire, renewing a
+// session token when needed. API keys and session tokens without an expiry
+// are returned as they are.
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	token := c.Token()
+
+	if !expiresWithin(token, c.refreshBefore) {
+		return token, nil
+	}
+
+	return c.refresh(ctx, token)
+}
+
+// refresh trades stale for a token of the same session with a later expiry,
+// unless another caller already replaced it. No password is needed, so
+// sessions completed with a two-factor code are renewed as well.
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if token := c.Token(); token != stale {
+		return token, nil
+	}
+
+	resp, err := c.sendWithRetries(ctx, request{method: "POST", path: "/api/v1/me/token"}, nil, stale)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", newError(resp)
+	}
+
+	body := &types.PostTokenResponseBody{}
+
+	if err := decode(resp, body); err != nil {
+		return "", err
+	}
+
+	c.SetToken(body.Token)
+	return body.Token, nil
+}
+
+func expiresWithin(token string, d time.Duration) bool {
+	claims := &types.JWTClaims{}
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil || claims.ExpiresAt == 0 {
+		return false
+	}
+
+	return time.Until(time.Unix(claims.ExpiresAt, 0)) < d
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	auth   bool
+	once   bool
+}
+
+// do sends r and decodes a successful response into out, which may be nil or
+// a *[]byte for raw bodies.
+func (c *Client) do(ctx context.Context, r request, out interface{}) error {
+	resp, err := c.send(ctx, r)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return newError(resp)
+	}
+
+	return decode(resp, out)
+}
+
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+
+	if r.body != nil {
+		b, err := json.Marshal(r.body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = b
+	}
+
+	token := ""
+
+	if r.auth {
+		t, err := c.currentToken(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		token = t
+	}
+
+	return c.sendWithRetries(ctx, r, body, token)
+}
+
+func (c *Client) sendWithRetries(ctx context.Context, r request, body []byte, token string) (*http.Response, error) {
+	u := c.baseURL + r.path
+
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequestWithContext(ctx, r.method, u, bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/json")
+
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+
+		if retry == c.maxRetries || r.once || !retryable(r.method, resp, err) {
+			return resp, err
+		}
+
+		wait := c.backoff(retry)
+
+		if resp != nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// retryable reports whether a request may be sent again. Only idempotent
+// requests are retried: any other request may have taken effect even when
+// the server answered with an error.
+func retryable(method string, resp *http.Response, err error) bool {
+	if method != http.MethodGet && method != http.MethodDelete {
+		return false
+	}
+
+	return err != nil || resp.StatusCode >= 500
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+func exponentialBackoff(retry int) time.Duration {
+	d := 100 * time.Millisecond << uint(retry)
+
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func decode(resp *http.Response, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		b, err := io.ReadAll(resp.Body)
+		*raw = b
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,416 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/password"
+	"github.com/richardpanda/composition/server/api/router"
+	"github.com/richardpanda/composition/server/api/totp"
+	"github.com/richardpanda/composition/server/api/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ctx = context.Background()
+
+func newServer(t *testing.T, lifetimes types.TokenLifetimes) *httptest.Server {
+	key, err := keys.GenerateEd25519("test", keys.StateActive)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := keys.NewKeySet(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := password.NewPolicy(8, bcrypt.MinCost)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lifetimes.Challenge = 5 * time.Minute
+
+	srv := httptest.NewServer(router.New(models.NewMemoryStore(), router.Options{
+		Keys:           ks,
+		DeletionPolicy: models.DeletionPolicyDelete,
+		PasswordPolicy: policy,
+		TokenLifetimes: lifetimes,
+		Features:       router.Features{Signup: true, TwoFactor: true},
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func signup(t *testing.T, c *Client, username string) {
+	err := c.Signup(ctx, types.SignupRequestBody{
+		Username:        username,
+		Email:           username + "@test.com",
+		Password:        "correct horse battery",
+		PasswordConfirm: "correct horse battery",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArticles(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{})
+	c := New(srv.URL, Options{})
+
+	signup(t, c, "author")
+
+	for i := 1; i <= 25; i++ {
+		a, err := c.CreateArticle(ctx, types.PostArticlesRequestBody{Title: fmt.Sprintf("Title %d", i), Body: "Body"})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.ArticleID != i {
+			t.Fatalf("got article %d, want %d", a.ArticleID, i)
+		}
+	}
+
+	a, err := c.GetArticle(ctx, 3)
+
+	if err != nil || a.Title != "Title 3" || !a.IsAuthor {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+
+	it := New(srv.URL, Options{}).ArticlePreviews(ctx)
+	want := 25
+
+	for it.Next() {
+		if p := it.Preview(); p.ID != want {
+			t.Fatalf("got article %d, want %d", p.ID, want)
+		}
+		want--
+	}
+
+	if it.Err() != nil || want != 0 {
+		t.Fatalf("stopped before article %d: %v", want, it.Err())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{})
+	c := New(srv.URL, Options{})
+
+	err := c.Signin(ctx, types.SigninRequestBody{Username: "nobody", Password: "password"})
+
+	if !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v", err)
+	}
+
+	err = c.Signup(ctx, types.SignupRequestBody{Email: "invalid"})
+
+	var e *Error
+
+	if !errors.As(err, &e) || e.Status != 400 || !errors.Is(err, ErrValidation) {
+		t.Fatalf("got %v", err)
+	}
+
+	if len(e.Fields) != 4 || e.Fields[0].Field != "username" {
+		t.Fatalf("got %+v", e.Fields)
+	}
+
+	if _, err := c.GetArticle(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v", err)
+	}
+
+	if _, err := c.GetAPIKeys(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		method   string
+		status   int
+		failures int32
+		attempts int32
+		ok       bool
+	}{
+		{"GET", 502, 2, 3, true},
+		{"GET", 500, 10, 4, false},
+		{"POST", 503, 1, 1, false},
+		{"POST", 500, 1, 1, false},
+	}
+
+	for _, test := range tests {
+		srv := newServer(t, types.TokenLifetimes{})
+
+		var attempts int32
+
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == test.method && atomic.AddInt32(&attempts, 1) <= test.failures {
+				w.WriteHeader(test.status)
+				return
+			}
+
+			srv.Config.Handler.ServeHTTP(w, req)
+		}))
+
+		c := New(flaky.URL, Options{Backoff: func(int) time.Duration { return 0 }})
+
+		var err error
+
+		if test.method == "GET" {
+			_, err = c.GetArticlePreviews(ctx, 1)
+		} else {
+			signup(t, New(srv.URL, Options{}), "author")
+			err = c.Signin(ctx, types.SigninRequestBody{Username: "author", Password: "correct horse battery"})
+		}
+
+		flaky.Close()
+
+		if attempts != test.attempts || (err == nil) != test.ok {
+			t.Fatalf("%s %d: got %d attempts, %v", test.method, test.status, attempts, err)
+		}
+
+		var e *Error
+
+		if !test.ok && (!errors.As(err, &e) || e.Status != test.status) {
+			t.Fatalf("got %v", err)
+		}
+	}
+}
+
+func TestRetriesStopWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, Options{Backoff: func(int) time.Duration { return time.Hour }})
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Healthz(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// countTokens serves srv and counts requests that renew a token or sign in.
+func countTokens(t *testing.T, srv *httptest.Server, refreshes, signins *int32) *httptest.Server {
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v1/me/token":
+			atomic.AddInt32(refreshes, 1)
+		case "/api/v1/signin", "/api/v1/signin/2fa":
+			atomic.AddInt32(signins, 1)
+		}
+
+		srv.Config.Handler.ServeHTTP(w, req)
+	}))
+
+	t.Cleanup(counting.Close)
+
+	return counting
+}
+
+func TestRevokedSessionIsNotRenewed(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{Session: time.Hour})
+
+	var refreshes, signins int32
+
+	c := New(countTokens(t, srv, &refreshes, &signins).URL, Options{})
+	other := New(srv.URL, Options{})
+
+	signup(t, c, "author")
+
+	if err := other.Signin(ctx, types.SigninRequestBody{Username: "author", Password: "correct horse battery"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := other.GetSessions(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range sessions {
+		if !s.Current {
+			if err := other.RevokeSession(ctx, s.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := c.GetAPIKeys(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v", err)
+	}
+
+	if refreshes != 0 || signins != 0 {
+		t.Fatalf("got %d refreshes and %d sign ins", refreshes, signins)
+	}
+}
+
+func TestRefreshBeforeExpiry(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{Session: time.Hour})
+
+	var refreshes, signins int32
+
+	url := countTokens(t, srv, &refreshes, &signins).URL
+	c := New(url, Options{RefreshBefore: 2 * time.Hour})
+
+	signup(t, c, "author")
+
+	if _, err := c.GetSessions(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshes != 1 || signins != 0 {
+		t.Fatalf("got %d refreshes and %d sign ins", refreshes, signins)
+	}
+
+	if sessions, err := c.GetSessions(ctx); err != nil || len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("renewal changed the session: %+v, %v", sessions, err)
+	}
+
+	c = New(url, Options{})
+
+	if err := c.Signin(ctx, types.SigninRequestBody{Username: "author", Password: "correct horse battery"}); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshes = 0
+
+	if _, err := c.GetSessions(ctx); err != nil || refreshes != 0 {
+		t.Fatalf("token was renewed early: %v", err)
+	}
+}
+
+func TestTwoFactorSignin(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{Session: time.Hour})
+	c := New(srv.URL, Options{})
+
+	signup(t, c, "author")
+
+	setup, err := c.SetupTwoFactor(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := totp.Code(setup.Secret, time.Now())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confirm, err := c.ConfirmTwoFactor(ctx, types.TwoFactorConfirmRequestBody{Code: code})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var refreshes, signins int32
+
+	c = New(countTokens(t, srv, &refreshes, &signins).URL, Options{RefreshBefore: 2 * time.Hour})
+	err = c.Signin(ctx, types.SigninRequestBody{Username: "author", Password: "correct horse battery"})
+
+	var challenge *ChallengeError
+
+	if !errors.As(err, &challenge) || c.Token() != "" {
+		t.Fatalf("got %v", err)
+	}
+
+	err = c.SigninTwoFactor(ctx, types.SigninTwoFactorRequestBody{
+		ChallengeToken: challenge.ChallengeToken,
+		Code:           confirm.RecoveryCodes[0],
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetSessions(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshes != 1 || signins != 2 {
+		t.Fatalf("got %d refreshes and %d sign ins", refreshes, signins)
+	}
+}
+
+func TestAPIKeyToken(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{})
+	c := New(srv.URL, Options{})
+
+	signup(t, c, "author")
+
+	k, err := c.CreateAPIKey(ctx, types.PostAPIKeysRequestBody{Name: "ci", Scopes: []string{types.ScopeArticlesWrite}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := New(srv.URL, Options{Token: k.Key})
+
+	if _, err := bot.CreateArticle(ctx, types.PostArticlesRequestBody{Title: "Title", Body: "Body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.GetAPIKeys(ctx); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v", err)
+	}
+
+	if err := c.DeleteAPIKey(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.CreateArticle(ctx, types.PostArticlesRequestBody{Title: "Title", Body: "Body"}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestAccount(t *testing.T) {
+	srv := newServer(t, types.TokenLifetimes{})
+	c := New(srv.URL, Options{})
+
+	signup(t, c, "author")
+
+	archive, err := c.Export(ctx)
+
+	if err != nil || len(archive) < 4 || string(archive[:2]) != "PK" {
+		t.Fatalf("got %d bytes, %v", len(archive), err)
+	}
+
+	err = c.ChangePassword(ctx, types.ChangePasswordRequestBody{
+		CurrentPassword:    "correct horse battery",
+		NewPassword:        "staple battery horse",
+		NewPasswordConfirm: "staple battery horse",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.DeleteAccount(ctx, types.DeleteMeRequestBody{Password: "staple battery horse"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Token() != "" {
+		t.Fatal("token was kept")
+	}
+
+	if _, err := c.GetJWKS(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// Error is a failed response. Compare it with the sentinel errors below using
+// errors.Is, which matches on Code.
+type Error struct {
+	Status     int
+	Code       string
+	Message    string
+	Fields     []types.FieldError
+	RetryAfter time.Duration
+}
+
+var (
+	ErrBadRequest      = &Error{Code: "bad_request"}
+	ErrConflict        = &Error{Code: "conflict"}
+	ErrForbidden       = &Error{Code: "forbidden"}
+	ErrInternal        = &Error{Code: "internal"}
+	ErrNotFound        = &Error{Code: "not_found"}
+	ErrTimeout         = &Error{Code: "timeout"}
+	ErrTooManyRequests = &Error{Code: "too_many_requests"}
+	ErrUnauthorized    = &Error{Code: "unauthorized"}
+	ErrUnavailable     = &Error{Code: "unavailable"}
+	ErrUpstream        = &Error{Code: "upstream_failed"}
+	ErrValidation      = &Error{Code: "validation_failed"}
+)
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("composition: %d %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("composition: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// ChallengeError is returned by Signin when the account has two-factor
+// authentication enabled. Pass ChallengeToken to SigninTwoFactor.
+type ChallengeError struct {
+	ChallengeToken string
+}
+
+func (e *ChallengeError) Error() string {
+	return "composition: two-factor code required"
+}
+
+func newError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	if d, ok := retryAfter(resp); ok {
+		e.RetryAfter = d
+	}
+
+	b, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	body := &types.ErrorResponseBody{}
+
+	if json.Unmarshal(b, body) == nil && body.Code != "" {
+		e.Code = body.Code
+		e.Message = body.Message
+		e.Fields = body.Errors
+	}
+
+	return e
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func (c *Client) Healthz(ctx context.Context) (*types.HealthResponseBody, error) {
+	resp := &types.HealthResponseBody{}
+
+	if err := c.do(ctx, request{method: "GET", path: "/healthz"}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Readyz reports every readiness check. When a required check fails it
+// returns the report along with an error matching ErrUnavailable.
+func (c *Client) Readyz(ctx context.Context) (*types.ReadinessResponseBody, error) {
+	resp, err := c.send(ctx, request{method: "GET", path: "/readyz", once: true})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, newError(resp)
+	}
+
+	body := &types.ReadinessResponseBody{}
+
+	if err := decode(resp, body); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return body, &Error{Status: resp.StatusCode, Code: ErrUnavailable.Code, Message: "Service is unavailable."}
+	}
+
+	return body, nil
+}
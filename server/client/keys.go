@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// GetJWKS returns the public keys that verify session tokens.
+func (c *Client) GetJWKS(ctx context.Context) (*types.JWKSResponseBody, error) {
+	resp := &types.JWKSResponseBody{}
+
+	if err := c.do(ctx, request{method: "GET", path: "/.well-known/jwks.json"}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"context"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+// ChangePassword changes the password. The current session stays valid.
+func (c *Client) ChangePassword(ctx context.Context, body types.ChangePasswordRequestBody) error {
+	return c.do(ctx, request{method: "POST", path: "/api/v1/me/password", body: body, auth: true}, nil)
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func (c *Client) GetSessions(ctx context.Context) ([]types.Session, error) {
+	resp := &types.GetSessionsResponseBody{}
+
+	if err := c.do(ctx, request{method: "GET", path: "/api/v1/me/sessions", auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Sessions, nil
+}
+
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/api/v1/me/sessions/" + url.PathEscape(id), auth: true}, nil)
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+
+	"github.com/richardpanda/composition/server/api/types"
+)
+
+func (c *Client) ConfirmTwoFactor(ctx context.Context, body types.TwoFactorConfirmRequestBody) (*types.TwoFactorConfirmResponseBody, error) {
+	resp := &types.TwoFactorConfirmResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/me/2fa/confirm", body: body, auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *Client) SetupTwoFactor(ctx context.Context) (*types.TwoFactorSetupResponseBody, error) {
+	resp := &types.TwoFactorSetupResponseBody{}
+
+	if err := c.do(ctx, request{method: "POST", path: "/api/v1/me/2fa/setup", auth: true}, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
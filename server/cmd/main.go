@@ -1,45 +1,173 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"log"
-	"net/http"
 	"os"
 
+	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
+	"github.com/richardpanda/composition/server/api/health"
+	"github.com/richardpanda/composition/server/api/keys"
+	"github.com/richardpanda/composition/server/api/logging"
+	"github.com/richardpanda/composition/server/api/metrics"
 	"github.com/richardpanda/composition/server/api/models"
+	"github.com/richardpanda/composition/server/api/oidc"
+	"github.com/richardpanda/composition/server/api/password"
 	"github.com/richardpanda/composition/server/api/router"
+	"github.com/richardpanda/composition/server/api/tracing"
+	"github.com/richardpanda/composition/server/api/types"
+	"github.com/richardpanda/composition/server/config"
+	"github.com/richardpanda/composition/server/httpserver"
+	"github.com/richardpanda/composition/server/migrations"
 	"github.com/richardpanda/composition/server/seeder"
+	"github.com/richardpanda/composition/server/workers"
+	"go.opentelemetry.io/otel"
 )
 
 func main() {
-	env := os.Getenv("ENVIRONMENT")
-	user := os.Getenv("DB_USER")
-	dbname := os.Getenv("DB_NAME")
-	connectionString := fmt.Sprintf("user=%s dbname=%s sslmode=disable", user, dbname)
+	if len(os.Args) > 2 && os.Args[1] == "migrate" && os.Args[2] == "create" {
+		createMigration(os.Args[3:])
+		return
+	}
+
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Level)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(logger)
+
+	if cfg.Environment != "dev" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	tp, err := tracing.NewProvider(context.Background(), cfg.Tracing)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(tracing.Propagator())
+
+	db, err := sql.Open("postgres", cfg.Database.DSN())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
-	db, err := sql.Open("postgres", connectionString)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ReadinessTimeout)
+	err = db.PingContext(ctx)
+	cancel()
+
+	if err != nil {
+		log.Fatalf("database is unreachable: %v", err)
+	}
+
+	m, err := migrations.New(db)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = models.CreateUsersTable(db)
+	if len(args) > 0 && args[0] == "migrate" {
+		migrate(m, args[1:])
+		return
+	}
+
+	policy, err := models.ParseDeletionPolicy(cfg.Auth.DeletionPolicy)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	_, err = models.CreateArticlesTable(db)
+	ks, err := keys.Load(cfg.Auth.KeySet)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if env == "dev" {
-		seeder.PopulateDB(db)
+	passwordPolicy, err := password.NewPolicy(cfg.Auth.PasswordMinLength, cfg.Auth.BcryptCost)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Environment == "dev" {
+		if err := seeder.PopulateDB(db); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	http.ListenAndServe(":8080", router.New(db))
+	store := models.NewPostgresStore(db, cfg.Database.QueryTimeout)
+	prom := metrics.New()
+	prom.RegisterDB(db)
+	bg := workers.NewGroup(
+		workers.PruneSessions(store, cfg.Workers.SessionPruneInterval, cfg.Workers.SessionRetention),
+		workers.PruneChallenges(store, cfg.Workers.SessionPruneInterval),
+	)
+
+	deprecations := map[string]router.Deprecation{}
+
+	if deprecatedAt, sunset := cfg.API.V1Deprecation(); !deprecatedAt.IsZero() || !sunset.IsZero() {
+		deprecations["v1"] = router.Deprecation{DeprecatedAt: deprecatedAt, Sunset: sunset}
+	}
+
+	srv, err := httpserver.New(cfg.Server, router.New(store, router.Options{
+		TrustedProxies: cfg.Server.TrustedProxies,
+		Keys:           ks,
+		Providers:      oidc.NewProviders(cfg.OIDC),
+		DeletionPolicy: policy,
+		PasswordPolicy: passwordPolicy,
+		TokenLifetimes: types.TokenLifetimes{
+			Session:          cfg.Auth.SessionTokenLifetime,
+			Challenge:        cfg.Auth.ChallengeTokenLifetime,
+			OIDCState:        cfg.Auth.OIDCStateLifetime,
+			Reauthentication: cfg.Auth.ReauthenticationWindow,
+		},
+		Features: router.Features{
+			Signup:    cfg.Features.Signup,
+			OIDC:      cfg.Features.OIDC,
+			TwoFactor: cfg.Features.TwoFactor,
+		},
+		Health: &health.Checker{
+			Timeout: cfg.Server.ReadinessTimeout,
+			Checks: []health.Check{
+				health.Database(db),
+				health.Migrations(m),
+				health.Workers(bg),
+			},
+		},
+		Metrics:      prom,
+		Logger:       logger,
+		Deprecations: deprecations,
+	}))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bg.Start()
+
+	if err := serve(srv, bg, db, tp, cfg.Server.ShutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/richardpanda/composition/server/migrations"
+)
+
+const migrateUsage = "usage: migrate up | down [-steps n] | status | create [-dir path] <name>"
+
+func migrate(m *migrations.Migrator, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+
+		for _, mig := range applied {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		fs := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		fs.Parse(args[1:])
+
+		reverted, err := m.Down(*steps)
+
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := m.Status()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, s := range statuses {
+			applied := "pending"
+
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+func createMigration(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "directory containing migration files")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	up, down, err := migrations.Create(*dir, fs.Arg(0))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("created %s\ncreated %s\n", up, down)
+}
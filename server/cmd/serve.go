@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/richardpanda/composition/server/httpserver"
+	"github.com/richardpanda/composition/server/workers"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func serve(srv *httpserver.Server, bg *workers.Group, db *sql.DB, tp *sdktrace.TracerProvider, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	var serveErr error
+
+loop:
+	for {
+		select {
+		case serveErr = <-errs:
+			break loop
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				if err := srv.Reload(); err != nil {
+					log.Printf("reloading TLS certificate: %v", err)
+				}
+				continue
+			}
+
+			log.Printf("received %s, shutting down", sig)
+			break loop
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("draining requests: %v", err)
+	}
+
+	if err := bg.Stop(ctx); err != nil {
+		log.Printf("stopping workers: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		log.Printf("closing database: %v", err)
+	}
+
+	if err := tp.Shutdown(ctx); err != nil {
+		log.Printf("flushing traces: %v", err)
+	}
+
+	return serveErr
+}
@@ -0,0 +1,472 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	Environment string   `yaml:"environment" env:"ENVIRONMENT" flag:"env"`
+	Server      Server   `yaml:"server"`
+	Database    Database `yaml:"database"`
+	Auth        Auth     `yaml:"auth"`
+	Features    Features `yaml:"features"`
+	OIDC        OIDC     `yaml:"oidc"`
+	API         API      `yaml:"api"`
+	Workers     Workers  `yaml:"workers"`
+	Log         Log      `yaml:"log"`
+	Tracing     Tracing  `yaml:"tracing"`
+}
+
+type Server struct {
+	Addr              string        `yaml:"addr" env:"LISTEN_ADDR" flag:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" env:"MAX_HEADER_BYTES"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	ReadinessTimeout  time.Duration `yaml:"readiness_timeout" env:"READINESS_TIMEOUT"`
+	TLSCertFile       string        `yaml:"tls_cert_file" env:"TLS_CERT_FILE" flag:"tls-cert"`
+	TLSKeyFile        string        `yaml:"tls_key_file" env:"TLS_KEY_FILE" flag:"tls-key"`
+	TrustedProxies    []string      `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies"`
+}
+
+type Database struct {
+	Host            string        `yaml:"host" env:"DB_HOST" flag:"db-host"`
+	Port            int           `yaml:"port" env:"DB_PORT" flag:"db-port"`
+	User            string        `yaml:"user" env:"DB_USER" flag:"db-user"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD"`
+	Name            string        `yaml:"name" env:"DB_NAME" flag:"db-name"`
+	SSLMode         string        `yaml:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	QueryTimeout    time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+}
+
+type Auth struct {
+	KeySet                 string        `yaml:"keyset" env:"JWT_KEYSET" flag:"keyset"`
+	SessionTokenLifetime   time.Duration `yaml:"session_token_lifetime" env:"SESSION_TOKEN_LIFETIME"`
+	ChallengeTokenLifetime time.Duration `yaml:"challenge_token_lifetime" env:"CHALLENGE_TOKEN_LIFETIME"`
+	OIDCStateLifetime      time.Duration `yaml:"oidc_state_lifetime" env:"OIDC_STATE_LIFETIME"`
+	ReauthenticationWindow time.Duration `yaml:"reauthentication_window" env:"REAUTHENTICATION_WINDOW"`
+	PasswordMinLength      int           `yaml:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	BcryptCost             int           `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	DeletionPolicy         string        `yaml:"deletion_policy" env:"ACCOUNT_DELETION_POLICY"`
+}
+
+type Features struct {
+	Signup    bool `yaml:"signup" env:"FEATURE_SIGNUP"`
+	OIDC      bool `yaml:"oidc" env:"FEATURE_OIDC"`
+	TwoFactor bool `yaml:"two_factor" env:"FEATURE_TWO_FACTOR"`
+}
+
+// OIDC lists the OpenID Connect providers users may sign in with, keyed by
+// the name used in /auth/<name>/start.
+type OIDC struct {
+	Providers map[string]OIDCProvider `yaml:"providers"`
+}
+
+type OIDCProvider struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+type API struct {
+	V1DeprecatedAt string `yaml:"v1_deprecated_at" env:"API_V1_DEPRECATED_AT"`
+	V1Sunset       string `yaml:"v1_sunset" env:"API_V1_SUNSET"`
+}
+
+type Workers struct {
+	SessionPruneInterval time.Duration `yaml:"session_prune_interval" env:"SESSION_PRUNE_INTERVAL"`
+	SessionRetention     time.Duration `yaml:"session_retention" env:"SESSION_RETENTION"`
+}
+
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" flag:"log-level"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+var (
+	sslModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels = []string{"debug", "info", "warn", "error"}
+	exporters = []string{"none", "stdout", "otlp"}
+)
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   30 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+		},
+		Database: Database{
+			Host:         "localhost",
+			Port:         5432,
+			SSLMode:      "disable",
+			MaxOpenConns: 25,
+			MaxIdleConns: 5,
+			QueryTimeout: 5 * time.Second,
+		},
+		Auth: Auth{
+			ChallengeTokenLifetime: 5 * time.Minute,
+			OIDCStateLifetime:      10 * time.Minute,
+			ReauthenticationWindow: 10 * time.Minute,
+			PasswordMinLength:      8,
+			BcryptCost:             10,
+			DeletionPolicy:         "delete",
+		},
+		Features: Features{
+			Signup:    true,
+			OIDC:      true,
+			TwoFactor: true,
+		},
+		Workers: Workers{
+			SessionPruneInterval: time.Hour,
+			SessionRetention:     30 * 24 * time.Hour,
+		},
+		Log: Log{
+			Level: "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "composition",
+			SampleRatio: 1,
+		},
+	}
+}
+
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	c := Default()
+	fs := flag.NewFlagSet("composition", flag.ContinueOnError)
+	path := fs.String("config", getenv("CONFIG_FILE"), "path to a YAML configuration file")
+
+	walk(reflect.ValueOf(c).Elem(), "", func(name string, f reflect.Value, tag reflect.StructTag) {
+		if fl := tag.Get("flag"); fl != "" {
+			fs.String(fl, "", "overrides "+name)
+		}
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path != "" {
+		b, err := os.ReadFile(*path)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: %v", err)
+		}
+
+		if err := yaml.UnmarshalStrict(b, c); err != nil {
+			return nil, nil, fmt.Errorf("config: %s: %v", *path, err)
+		}
+	}
+
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+
+	var problems []string
+
+	walk(reflect.ValueOf(c).Elem(), "", func(name string, f reflect.Value, tag reflect.StructTag) {
+		if env := tag.Get("env"); env != "" && getenv(env) != "" {
+			if err := setField(f, getenv(env)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (from %s): %v", name, env, err))
+			}
+		}
+
+		if v, ok := set[tag.Get("flag")]; ok {
+			if err := setField(f, v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (from -%s): %v", name, tag.Get("flag"), err))
+			}
+		}
+	})
+
+	c.OIDC.loadEnv(getenv)
+
+	if len(problems) == 0 {
+		problems = c.problems()
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, errors.New("config: invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+
+	return c, fs.Args(), nil
+}
+
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return errors.New("config: invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func (c *Config) problems() []string {
+	var problems []string
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	srv, d, a, w := c.Server, c.Database, c.Auth, c.Workers
+
+	check(srv.Addr != "", "server.addr is required")
+	check(srv.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(srv.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative")
+	check(srv.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(srv.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(srv.MaxHeaderBytes > 0, "server.max_header_bytes must be positive")
+	check(srv.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(srv.ReadinessTimeout > 0, "server.readiness_timeout must be positive")
+	check((srv.TLSCertFile == "") == (srv.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+
+	for _, proxy := range srv.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies must list IP addresses or CIDR ranges, got %q", proxy)
+	}
+
+	check(d.Host != "", "database.host is required")
+	check(d.Port > 0 && d.Port <= 65535, "database.port must be between 1 and 65535, got %d", d.Port)
+	check(d.Name != "", "database.name is required")
+	check(contains(sslModes, d.SSLMode), "database.sslmode must be one of %s, got %q", strings.Join(sslModes, ", "), d.SSLMode)
+	check(d.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(d.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(d.MaxOpenConns == 0 || d.MaxIdleConns <= d.MaxOpenConns, "database.max_idle_conns must not exceed database.max_open_conns")
+	check(d.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(d.QueryTimeout >= 0, "database.query_timeout must not be negative")
+	check(a.SessionTokenLifetime >= 0, "auth.session_token_lifetime must not be negative")
+	check(a.ChallengeTokenLifetime > 0, "auth.challenge_token_lifetime must be positive")
+	check(a.OIDCStateLifetime > 0, "auth.oidc_state_lifetime must be positive")
+	check(a.ReauthenticationWindow > 0, "auth.reauthentication_window must be positive")
+	check(a.PasswordMinLength >= 1 && a.PasswordMinLength <= 72, "auth.password_min_length must be between 1 and 72, got %d", a.PasswordMinLength)
+	check(a.BcryptCost >= 4 && a.BcryptCost <= 31, "auth.bcrypt_cost must be between 4 and 31, got %d", a.BcryptCost)
+	check(a.DeletionPolicy == "delete" || a.DeletionPolicy == "anonymize", "auth.deletion_policy must be delete or anonymize, got %q", a.DeletionPolicy)
+
+	names := make([]string, 0, len(c.OIDC.Providers))
+
+	for name := range c.OIDC.Providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		p, key := c.OIDC.Providers[name], "oidc.providers."+name
+
+		check(absoluteURL(p.Issuer), "%s.issuer must be an absolute URL, got %q", key, p.Issuer)
+		check(p.ClientID != "", "%s.client_id is required", key)
+		check(p.ClientSecret != "", "%s.client_secret is required", key)
+		check(absoluteURL(p.RedirectURL), "%s.redirect_url must be an absolute URL, got %q", key, p.RedirectURL)
+	}
+
+	deprecatedAt, err1 := parseDate(c.API.V1DeprecatedAt)
+	sunset, err2 := parseDate(c.API.V1Sunset)
+
+	check(err1 == nil, "api.v1_deprecated_at must be a date like 2027-01-31, got %q", c.API.V1DeprecatedAt)
+	check(err2 == nil, "api.v1_sunset must be a date like 2027-01-31, got %q", c.API.V1Sunset)
+	check(sunset.IsZero() || !sunset.Before(deprecatedAt), "api.v1_sunset must not be before api.v1_deprecated_at")
+	check(w.SessionPruneInterval > 0, "workers.session_prune_interval must be positive")
+	check(w.SessionRetention >= 0, "workers.session_retention must not be negative")
+	check(contains(logLevels, c.Log.Level), "log.level must be one of %s, got %q", strings.Join(logLevels, ", "), c.Log.Level)
+	check(contains(exporters, c.Tracing.Exporter), "tracing.exporter must be one of %s, got %q", strings.Join(exporters, ", "), c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	return problems
+}
+
+// loadEnv adds the providers named in OIDC_PROVIDERS, reading each from
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES.
+// Set variables override the same provider's values from the file.
+func (o *OIDC) loadEnv(getenv func(string) string) {
+	for _, name := range strings.Split(getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		if o.Providers == nil {
+			o.Providers = map[string]OIDCProvider{}
+		}
+
+		p := o.Providers[name]
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		for key, dst := range map[string]*string{
+			"ISSUER":        &p.Issuer,
+			"CLIENT_ID":     &p.ClientID,
+			"CLIENT_SECRET": &p.ClientSecret,
+			"REDIRECT_URL":  &p.RedirectURL,
+		} {
+			if v := getenv(prefix + key); v != "" {
+				*dst = v
+			}
+		}
+
+		if scopes := getenv(prefix + "SCOPES"); scopes != "" {
+			p.Scopes = strings.FieldsFunc(scopes, func(r rune) bool { return r == ',' || r == ' ' })
+		}
+
+		o.Providers[name] = p
+	}
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// V1Deprecation returns when /api/v1 was or will be deprecated and removed,
+// as zero times when not announced.
+func (a API) V1Deprecation() (deprecatedAt, sunset time.Time) {
+	deprecatedAt, _ = parseDate(a.V1DeprecatedAt)
+	sunset, _ = parseDate(a.V1Sunset)
+	return deprecatedAt, sunset
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+func (d Database) DSN() string {
+	parts := []string{}
+
+	for _, kv := range [][2]string{
+		{"host", d.Host},
+		{"port", strconv.Itoa(d.Port)},
+		{"user", d.User},
+		{"password", d.Password},
+		{"dbname", d.Name},
+		{"sslmode", d.SSLMode},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+quote(kv[1]))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func walk(v reflect.Value, prefix string, fn func(string, reflect.Value, reflect.StructTag)) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), name+".", fn)
+			continue
+		}
+
+		fn(name, v.Field(i), field.Tag)
+	}
+}
+
+func setField(f reflect.Value, s string) error {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+
+		f.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+
+		f.SetBool(b)
+	case reflect.Slice:
+		var items []string
+
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		f.Set(reflect.ValueOf(items))
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func writeFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, args, err := Load(nil, env(map[string]string{"DB_NAME": "composition"}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Server.Addr != ":8080" || c.Auth.ChallengeTokenLifetime != 5*time.Minute || !c.Features.Signup || len(args) != 0 {
+		t.Fatalf("got %+v, %v", c, args)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  addr: ":9000"
+database:
+  name: from_file
+  user: file_user
+  max_open_conns: 10
+auth:
+  session_token_lifetime: 24h
+features:
+  signup: false
+`)
+
+	c, args, err := Load([]string{"-config", path, "-db-name", "from_flag", "migrate", "up"}, env(map[string]string{
+		"DB_NAME":      "from_env",
+		"DB_USER":      "env_user",
+		"FEATURE_OIDC": "false",
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Server.Addr != ":9000" || c.Database.MaxOpenConns != 10 || c.Auth.SessionTokenLifetime != 24*time.Hour {
+		t.Fatalf("file values not applied: %+v", c)
+	}
+
+	if c.Database.User != "env_user" || c.Features.OIDC || c.Features.Signup {
+		t.Fatalf("env values not applied: %+v", c)
+	}
+
+	if c.Database.Name != "from_flag" {
+		t.Fatalf("got %q, want from_flag", c.Database.Name)
+	}
+
+	if strings.Join(args, " ") != "migrate up" {
+		t.Fatalf("got %v", args)
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{
+		"DB_PORT":           "70000",
+		"DB_SSLMODE":        "sometimes",
+		"DB_MAX_OPEN_CONNS": "2",
+		"DB_MAX_IDLE_CONNS": "5",
+		"BCRYPT_COST":       "50",
+	}))
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"database.port", "database.name is required", "database.sslmode", "database.max_idle_conns", "auth.bcrypt_cost"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadRejectsMalformedValues(t *testing.T) {
+	_, _, err := Load(nil, env(map[string]string{
+		"DB_NAME":                "composition",
+		"DB_PORT":                "five",
+		"SESSION_TOKEN_LIFETIME": "forever",
+		"FEATURE_SIGNUP":         "maybe",
+	}))
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"DB_PORT", "SESSION_TOKEN_LIFETIME", "FEATURE_SIGNUP"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadAPIDeprecation(t *testing.T) {
+	c, _, err := Load(nil, env(map[string]string{
+		"DB_NAME":              "composition",
+		"API_V1_DEPRECATED_AT": "2027-01-01",
+		"API_V1_SUNSET":        "2027-07-01T12:00:00Z",
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deprecatedAt, sunset := c.API.V1Deprecation()
+
+	if !deprecatedAt.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) || !sunset.Equal(time.Date(2027, 7, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got %v, %v", deprecatedAt, sunset)
+	}
+
+	_, _, err = Load(nil, env(map[string]string{
+		"DB_NAME":              "composition",
+		"API_V1_DEPRECATED_AT": "soon",
+		"API_V1_SUNSET":        "2026-01-01",
+	}))
+
+	if err == nil || !strings.Contains(err.Error(), "api.v1_deprecated_at") {
+		t.Fatalf("got %v", err)
+	}
+
+	_, _, err = Load(nil, env(map[string]string{
+		"DB_NAME":              "composition",
+		"API_V1_DEPRECATED_AT": "2027-01-01",
+		"API_V1_SUNSET":        "2026-01-01",
+	}))
+
+	if err == nil || !strings.Contains(err.Error(), "api.v1_sunset must not be before") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	c, _, err := Load([]string{"-trusted-proxies", "10.0.0.0/8, 192.0.2.1"}, env(map[string]string{"DB_NAME": "composition"}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(c.Server.TrustedProxies, " ") != "10.0.0.0/8 192.0.2.1" {
+		t.Fatalf("got %q", c.Server.TrustedProxies)
+	}
+
+	_, _, err = Load(nil, env(map[string]string{
+		"DB_NAME":         "composition",
+		"TRUSTED_PROXIES": "10.0.0.0/33",
+	}))
+
+	if err == nil || !strings.Contains(err.Error(), "server.trusted_proxies") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeFile(t, "database:\n  nmae: composition\n")
+
+	if _, _, err := Load([]string{"-config", path}, env(nil)); err == nil || !strings.Contains(err.Error(), "nmae") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestDSN(t *testing.T) {
+	d := Database{Host: "db", Port: 5432, User: "app", Password: "it's a secret", Name: "composition", SSLMode: "require"}
+	want := `host=db port=5432 user=app password='it\'s a secret' dbname=composition sslmode=require`
+
+	if got := d.DSN(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	path := writeFile(t, `
+oidc:
+  providers:
+    google:
+      issuer: https://accounts.google.com
+      client_id: file_id
+      client_secret: file_secret
+      redirect_url: https://example.com/api/v1/auth/google/callback
+`)
+
+	c, _, err := Load([]string{"-config", path}, env(map[string]string{
+		"DB_NAME":               "composition",
+		"OIDC_PROVIDERS":        "google",
+		"OIDC_GOOGLE_CLIENT_ID": "env_id",
+		"OIDC_GOOGLE_SCOPES":    "openid email",
+		"OIDC_GITLAB_CLIENT_ID": "ignored",
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := c.OIDC.Providers["google"]
+
+	if len(c.OIDC.Providers) != 1 || p.ClientID != "env_id" || p.ClientSecret != "file_secret" || strings.Join(p.Scopes, " ") != "openid email" {
+		t.Fatalf("got %+v", c.OIDC.Providers)
+	}
+
+	_, _, err = Load(nil, env(map[string]string{
+		"DB_NAME":               "composition",
+		"OIDC_PROVIDERS":        "gitlab",
+		"OIDC_GITLAB_CLIENT_ID": "id",
+	}))
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"oidc.providers.gitlab.issuer", "oidc.providers.gitlab.client_secret", "oidc.providers.gitlab.redirect_url"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q does not mention %s", err, want)
+		}
+	}
+}
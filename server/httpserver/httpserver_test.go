@@ -0,0 +1,143 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/config"
+)
+
+func writeCert(t *testing.T, dir, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	s, err := New(config.Server{ReadTimeout: time.Second}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+
+	resp := make(chan *http.Response, 1)
+	go func() {
+		r, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			t.Error(err)
+		}
+		resp <- r
+	}()
+
+	<-started
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+
+	if r := <-resp; r == nil || r.StatusCode != 200 {
+		t.Fatalf("got %+v", r)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, "first")
+
+	r, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commonName := func() string {
+		cert, _ := r.GetCertificate(&tls.ClientHelloInfo{})
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return leaf.Subject.CommonName
+	}
+
+	if name := commonName(); name != "first" {
+		t.Fatalf("got %s", name)
+	}
+
+	writeCert(t, dir, "second")
+
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := commonName(); name != "second" {
+		t.Fatalf("got %s", name)
+	}
+
+	os.WriteFile(filepath.Join(dir, "key.pem"), []byte("garbage"), 0600)
+
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if name := commonName(); name != "second" {
+		t.Fatalf("got %s after a failed reload", name)
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+	id       SERIAL       PRIMARY KEY,
+	username VARCHAR(20)  UNIQUE NOT NULL,
+	email    VARCHAR(50)  UNIQUE NOT NULL,
+	password VARCHAR(255) NOT NULL
+);
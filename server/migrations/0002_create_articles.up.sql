@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS articles (
+	id         SERIAL       PRIMARY KEY,
+	user_id    SERIAL       REFERENCES users,
+	title      VARCHAR(100)  NOT NULL,
+	body       TEXT         NOT NULL,
+	created_at TIMESTAMP    NOT NULL
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS two_factor (
+	user_id   INTEGER     PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret    VARCHAR(64) NOT NULL,
+	enabled   BOOLEAN     NOT NULL DEFAULT FALSE,
+	last_step BIGINT      NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id        SERIAL      PRIMARY KEY,
+	user_id   INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash VARCHAR(64) NOT NULL,
+	used_at   TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS challenges (
+	id         VARCHAR(32) PRIMARY KEY,
+	user_id    INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at    TIMESTAMPTZ
+);
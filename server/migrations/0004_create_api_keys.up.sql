@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id           SERIAL       PRIMARY KEY,
+	user_id      INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name         VARCHAR(50)  NOT NULL,
+	prefix       VARCHAR(16)  NOT NULL,
+	key_hash     VARCHAR(64)  UNIQUE NOT NULL,
+	scopes       VARCHAR(255) NOT NULL,
+	created_at   TIMESTAMPTZ  NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	expires_at   TIMESTAMPTZ
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS identities (
+	id         SERIAL       PRIMARY KEY,
+	user_id    INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider   VARCHAR(50)  NOT NULL,
+	subject    VARCHAR(255) NOT NULL,
+	email      VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP    NOT NULL,
+	UNIQUE (provider, subject)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id           VARCHAR(32)  PRIMARY KEY,
+	user_id      INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	user_agent   VARCHAR(255) NOT NULL,
+	ip           VARCHAR(45)  NOT NULL,
+	created_at   TIMESTAMPTZ  NOT NULL,
+	last_seen_at TIMESTAMPTZ  NOT NULL,
+	expires_at   TIMESTAMPTZ,
+	revoked_at   TIMESTAMPTZ
+);
@@ -0,0 +1,318 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var embedded embed.FS
+
+const lockID int64 = 7263849105
+
+const createSchemaMigrationsTableQuery = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER      PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ  NOT NULL
+	);
+`
+const deleteSchemaMigrationQuery = "DELETE FROM schema_migrations WHERE version = $1;"
+const getSchemaMigrationsQuery = "SELECT version, applied_at FROM schema_migrations ORDER BY version;"
+const insertSchemaMigrationQuery = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW());"
+
+var (
+	fileName       = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	nameDisallowed = regexp.MustCompile("[^a-z0-9]+")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+
+		if e.IsDir() || m == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		b, err := fs.ReadFile(fsys, e.Name())
+
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrations: version %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migrations: version %d is missing an up migration", mig.Version)
+		}
+
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load(embedded)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(func(conn *sql.Conn, done map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+
+			if err := apply(conn, mig.Up, insertSchemaMigrationQuery, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migrations: %04d_%s up: %v", mig.Version, mig.Name, err)
+			}
+
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(func(conn *sql.Conn, done map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+
+			if err := apply(conn, mig.Down, deleteSchemaMigrationQuery, mig.Version); err != nil {
+				return fmt.Errorf("migrations: %04d_%s down: %v", mig.Version, mig.Name, err)
+			}
+
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(func(conn *sql.Conn, done map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			s := Status{Migration: mig}
+
+			if t, ok := done[mig.Version]; ok {
+				s.AppliedAt = &t
+			}
+
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (m *Migrator) withLock(fn func(*sql.Conn, map[int]time.Time) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1);", lockID); err != nil {
+		return err
+	}
+
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1);", lockID)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTableQuery); err != nil {
+		return err
+	}
+
+	done, err := applied(ctx, conn)
+
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, done)
+}
+
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	done, err := applied(ctx, m.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, mig := range m.migrations {
+		if _, ok := done[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+
+	return pending, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func applied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, getSchemaMigrationsQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	done := map[int]time.Time{}
+
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		done[version] = appliedAt
+	}
+
+	return done, rows.Err()
+}
+
+func apply(conn *sql.Conn, script, record string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(nameDisallowed.ReplaceAllString(strings.ToLower(name), "_"), "_")
+
+	if name == "" {
+		return "", "", errors.New("migrations: name is required")
+	}
+
+	existing, err := Load(os.DirFS(dir))
+
+	if err != nil {
+		return "", "", err
+	}
+
+	version := 1
+
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", version, name))
+	up, down := base+".up.sql", base+".down.sql"
+
+	if err := os.WriteFile(up, []byte("-- Write the up migration here.\n"), 0644); err != nil {
+		return "", "", err
+	}
+
+	if err := os.WriteFile(down, []byte("-- Write the down migration here.\n"), 0644); err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsAreSequential(t *testing.T) {
+	migrations, err := Load(embedded)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			t.Fatalf("migration %d_%s is out of sequence, want version %d", mig.Version, mig.Name, i+1)
+		}
+
+		if mig.Down == "" {
+			t.Fatalf("migration %d_%s has no down migration", mig.Version, mig.Name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_bio.up.sql":        {Data: []byte("ALTER TABLE users ADD bio TEXT;")},
+		"0002_add_bio.down.sql":      {Data: []byte("ALTER TABLE users DROP bio;")},
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users ();")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+
+	if migrations[0].Name != "create_users" || migrations[1].Name != "add_bio" {
+		t.Fatalf("migrations are not ordered by version: %+v", migrations)
+	}
+
+	if migrations[1].Down != "ALTER TABLE users DROP bio;" {
+		t.Fatalf("unexpected down migration %q", migrations[1].Down)
+	}
+}
+
+func TestLoadRejectsConflictingNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.up.sql":  {Data: []byte("CREATE TABLE users ();")},
+		"0001_create_people.up.sql": {Data: []byte("CREATE TABLE people ();")},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected an error for conflicting names")
+	}
+}
+
+func TestLoadRejectsMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected an error for a missing up migration")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "0007_existing.up.sql"), []byte("SELECT 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	up, down, err := Create(dir, "Add User Bio")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Base(up) != "0008_add_user_bio.up.sql" || filepath.Base(down) != "0008_add_user_bio.down.sql" {
+		t.Fatalf("unexpected file names %s and %s", up, down)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+}
@@ -1,11 +1,11 @@
 package seeder
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"log"
-	"os"
 
 	"github.com/richardpanda/composition/server/api/models"
 	"golang.org/x/crypto/bcrypt"
@@ -23,29 +23,26 @@ type user struct {
 	Articles []article `json:"articles"`
 }
 
-func PopulateDB(db *sql.DB) {
-	f, err := os.Open("./seeder/users.json")
+func PopulateDB(db *sql.DB) error {
+	b, err := ioutil.ReadFile("./seeder/users.json")
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	defer f.Close()
-
-	b, err := ioutil.ReadAll(f)
+	var users []user
 
-	if err != nil {
-		log.Fatal(err)
+	if err := json.Unmarshal(b, &users); err != nil {
+		return err
 	}
 
-	var users []user
-	json.Unmarshal(b, &users)
+	ctx := context.Background()
 
 	for _, user := range users {
 		hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.MinCost)
 
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		u := &models.User{
@@ -54,17 +51,40 @@ func PopulateDB(db *sql.DB) {
 			Password: string(hash),
 		}
 
-		var id int
-		models.CreateUser(db, u).Scan(&id)
+		err = models.Transact(ctx, db, func(tx *sql.Tx) error {
+			err := models.GetUserByUsername(ctx, tx, u.Username).Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.PasswordSet)
+
+			if err == nil {
+				return nil
+			}
+
+			if err != sql.ErrNoRows {
+				return err
+			}
+
+			if err := models.CreateUser(ctx, tx, u).Scan(&u.ID); err != nil {
+				return err
+			}
+
+			for _, article := range user.Articles {
+				a := &models.Article{
+					UserID: u.ID,
+					Title:  article.Title,
+					Body:   article.Body,
+				}
 
-		for _, article := range user.Articles {
-			a := &models.Article{
-				UserID: id,
-				Title:  article.Title,
-				Body:   article.Body,
+				if err := models.CreateArticle(ctx, tx, a).Scan(&a.ID); err != nil {
+					return err
+				}
 			}
 
-			models.CreateArticle(db, a)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("seeding %s: %w", user.Username, err)
 		}
 	}
+
+	return nil
 }
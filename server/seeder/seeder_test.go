@@ -0,0 +1,53 @@
+package seeder
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/richardpanda/composition/server/migrations"
+)
+
+func TestPopulateDBTwice(t *testing.T) {
+	dbname := os.Getenv("TEST_DB_NAME")
+
+	if dbname == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	db, err := sql.Open("postgres", fmt.Sprintf("user=%s dbname=%s sslmode=disable", os.Getenv("DB_USER"), dbname))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	m, err := migrations.New(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Down(math.MaxInt32); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Down(math.MaxInt32)
+
+	// PopulateDB reads ./seeder/users.json, relative to the server directory.
+	t.Chdir("..")
+
+	for run := 1; run <= 2; run++ {
+		if err := PopulateDB(db); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/models"
+)
+
+func PruneChallenges(store models.Store, interval time.Duration) Worker {
+	return Worker{
+		Name:     "prune_challenges",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := store.WithContext(ctx).PruneChallenges(time.Now())
+			return err
+		},
+	}
+}
@@ -0,0 +1,19 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/models"
+)
+
+func PruneSessions(store models.Store, interval, retention time.Duration) Worker {
+	return Worker{
+		Name:     "prune_sessions",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := store.WithContext(ctx).PruneSessions(time.Now().Add(-retention))
+			return err
+		},
+	}
+}
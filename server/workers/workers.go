@@ -0,0 +1,128 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Status struct {
+	Name      string
+	Running   bool
+	LastRun   time.Time
+	LastError string
+}
+
+type Group struct {
+	workers []Worker
+
+	mu     sync.Mutex
+	status map[string]*Status
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewGroup(ws ...Worker) *Group {
+	g := &Group{workers: ws, status: map[string]*Status{}}
+
+	for _, w := range ws {
+		g.status[w.Name] = &Status{Name: w.Name}
+	}
+
+	return g
+}
+
+func (g *Group) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+
+	for _, w := range g.workers {
+		g.setRunning(w.Name, true)
+		g.wg.Add(1)
+
+		go func(w Worker) {
+			defer g.wg.Done()
+			defer g.setRunning(w.Name, false)
+
+			ticker := time.NewTicker(w.Interval)
+			defer ticker.Stop()
+
+			for {
+				g.run(ctx, w)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(w)
+	}
+}
+
+func (g *Group) Stop(ctx context.Context) error {
+	if g.cancel == nil {
+		return nil
+	}
+
+	g.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Group) Status() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statuses := make([]Status, 0, len(g.workers))
+
+	for _, w := range g.workers {
+		statuses = append(statuses, *g.status[w.Name])
+	}
+
+	return statuses
+}
+
+func (g *Group) run(ctx context.Context, w Worker) {
+	err := w.Run(ctx)
+
+	if err != nil && ctx.Err() == nil {
+		log.Printf("worker %s: %v", w.Name, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := g.status[w.Name]
+	s.LastRun = time.Now()
+	s.LastError = ""
+
+	if err != nil {
+		s.LastError = err.Error()
+	}
+}
+
+func (g *Group) setRunning(name string, running bool) {
+	g.mu.Lock()
+	g.status[name].Running = running
+	g.mu.Unlock()
+}
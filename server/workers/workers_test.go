@@ -0,0 +1,101 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/richardpanda/composition/server/api/models"
+)
+
+func TestGroupRunsAndStops(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	g := NewGroup(
+		Worker{Name: "ok", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+			select {
+			case runs <- struct{}{}:
+			default:
+			}
+			return nil
+		}},
+		Worker{Name: "failing", Interval: time.Hour, Run: func(ctx context.Context) error {
+			return errors.New("boom")
+		}},
+	)
+
+	g.Start()
+
+	for i := 0; i < 3; i++ {
+		<-runs
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := g.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := g.Status()
+
+	if len(statuses) != 2 || statuses[0].Running || statuses[0].LastRun.IsZero() {
+		t.Fatalf("got %+v", statuses)
+	}
+
+	if statuses[1].LastError != "boom" {
+		t.Fatalf("got %+v", statuses[1])
+	}
+}
+
+func TestStopHonorsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGroup(Worker{Name: "stuck", Interval: time.Hour, Run: func(ctx context.Context) error {
+		<-release
+		return nil
+	}})
+
+	g.Start()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := g.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+// contextStore records the context the worker queries with.
+type contextStore struct {
+	models.Store
+	ctx context.Context
+}
+
+func (s *contextStore) WithContext(ctx context.Context) models.Store {
+	s.ctx = ctx
+	return s.Store
+}
+
+func TestPruneSessions(t *testing.T) {
+	store := &contextStore{Store: models.NewMemoryStore()}
+	userID, _ := store.CreateUser(&models.User{Username: "test", Email: "test@test.com"})
+	store.CreateSession(&models.Session{ID: "a", UserID: userID})
+	store.RevokeSession("a", userID)
+
+	w := PruneSessions(store, time.Hour, -time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.ctx != ctx {
+		t.Fatal("worker did not query with its context")
+	}
+
+	if _, err := store.GetSession("a"); err != models.ErrNotFound {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}